@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"dispatcherd/ratelimit"
+	"encoding/hex"
+	"slices"
+)
+
+type Scope string
+
+const (
+	ScopeMessageSend   Scope = "message:send"
+	ScopeMessageAck    Scope = "message:ack"
+	ScopeRulesRead     Scope = "rules:read"
+	ScopeRulesWrite    Scope = "rules:write"
+	ScopeAlertsRead    Scope = "alerts:read"
+	ScopeSilencesRead  Scope = "silences:read"
+	ScopeSilencesWrite Scope = "silences:write"
+	ScopeAdmin         Scope = "admin"
+)
+
+type APIKey struct {
+	Name    string `json:"name" validate:"required"`
+	KeyHash string `json:"keyHash" validate:"required_without=CertificateCommonName,omitempty,sha256"`
+	// authenticates clients presenting a verified TLS client certificate with this common name
+	CertificateCommonName string           `json:"certificateCommonName"`
+	Scopes                []Scope          `json:"scopes" validate:"dive,oneof=message:send message:ack rules:read rules:write alerts:read silences:read silences:write admin"`
+	Policy                *Policy          `json:"policy"`
+	RateLimit             *ratelimit.Limit `json:"rateLimit"`
+}
+
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func (k APIKey) Identity() *Identity {
+	return &Identity{
+		Name:      k.Name,
+		Scopes:    slices.Clone(k.Scopes),
+		Policy:    k.Policy,
+		RateLimit: k.RateLimit,
+	}
+}
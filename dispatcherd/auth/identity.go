@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	dispatcherdContext "dispatcherd/context"
+	"dispatcherd/ratelimit"
+	"slices"
+)
+
+type Identity struct {
+	Name      string
+	Scopes    []Scope
+	Policy    *Policy
+	RateLimit *ratelimit.Limit
+}
+
+func (i *Identity) HasScope(scope Scope) bool {
+	// admin implicitly grants every scope
+	return slices.Contains(i.Scopes, ScopeAdmin) || slices.Contains(i.Scopes, scope)
+}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	ctx = context.WithValue(ctx, dispatcherdContext.KeyClientID, identity.Name)
+	return context.WithValue(ctx, dispatcherdContext.KeyIdentity, identity)
+}
+
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(dispatcherdContext.KeyIdentity).(*Identity)
+	return identity, ok
+}
@@ -0,0 +1,42 @@
+package auth_test
+
+import (
+	"context"
+	"dispatcherd/auth"
+	dispatcherdContext "dispatcherd/context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashKey(t *testing.T) {
+	// echo -n "secret" | sha256sum
+	assert.Equal(t, "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", auth.HashKey("secret"))
+}
+
+func TestHasScope(t *testing.T) {
+	t.Run("granted scope", func(t *testing.T) {
+		identity := &auth.Identity{Name: "test", Scopes: []auth.Scope{auth.ScopeMessageSend}}
+		assert.True(t, identity.HasScope(auth.ScopeMessageSend))
+		assert.False(t, identity.HasScope(auth.ScopeRulesRead))
+	})
+
+	t.Run("admin grants all scopes", func(t *testing.T) {
+		identity := &auth.Identity{Name: "test", Scopes: []auth.Scope{auth.ScopeAdmin}}
+		assert.True(t, identity.HasScope(auth.ScopeMessageSend))
+		assert.True(t, identity.HasScope(auth.ScopeRulesWrite))
+	})
+}
+
+func TestIdentityContext(t *testing.T) {
+	_, ok := auth.IdentityFromContext(context.Background())
+	assert.False(t, ok)
+
+	identity := &auth.Identity{Name: "test"}
+	ctx := auth.WithIdentity(context.Background(), identity)
+
+	actual, ok := auth.IdentityFromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, identity, actual)
+	assert.Equal(t, "test", dispatcherdContext.ClientID(ctx))
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+var ErrTagNotAllowed = errors.New("tag not allowed")
+
+type Policy struct {
+	// restricts the tag names a client may send, all names are allowed if empty
+	AllowedTagNames []string `json:"allowedTagNames"`
+	// restricts the values of the given tags
+	AllowedTagValues   map[string][]string `json:"allowedTagValues"`
+	ForbiddenTagNames  []string            `json:"forbiddenTagNames"`
+	ForbiddenTagValues map[string][]string `json:"forbiddenTagValues"`
+	// tags set server side, overriding the tags sent by the client
+	ForcedTags map[string]string `json:"forcedTags"`
+	// restricts the dispatchers messages of the client may reach, all dispatchers are allowed if empty
+	AllowedDispatchers []string `json:"allowedDispatchers"`
+}
+
+// ApplyTags checks the tags of a message against the policy and returns a copy including the forced tags.
+func (p *Policy) ApplyTags(tags map[string]string) (map[string]string, error) {
+	if p == nil {
+		return tags, nil
+	}
+
+	for name, value := range tags {
+		if err := p.checkTag(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(p.ForcedTags) == 0 {
+		return tags, nil
+	}
+
+	result := make(map[string]string, len(tags)+len(p.ForcedTags))
+	maps.Copy(result, tags)
+	maps.Copy(result, p.ForcedTags)
+
+	return result, nil
+}
+
+func (p *Policy) checkTag(name string, value string) error {
+	if _, forced := p.ForcedTags[name]; forced {
+		// will be overridden anyway
+		return nil
+	}
+
+	if len(p.AllowedTagNames) > 0 && !slices.Contains(p.AllowedTagNames, name) {
+		return fmt.Errorf("%w: %s", ErrTagNotAllowed, name)
+	}
+
+	if slices.Contains(p.ForbiddenTagNames, name) {
+		return fmt.Errorf("%w: %s", ErrTagNotAllowed, name)
+	}
+
+	if allowed, ok := p.AllowedTagValues[name]; ok && !slices.Contains(allowed, value) {
+		return fmt.Errorf("%w: %s=%s", ErrTagNotAllowed, name, value)
+	}
+
+	if slices.Contains(p.ForbiddenTagValues[name], value) {
+		return fmt.Errorf("%w: %s=%s", ErrTagNotAllowed, name, value)
+	}
+
+	return nil
+}
+
+func (p *Policy) AllowsDispatcher(name string) bool {
+	if p == nil || len(p.AllowedDispatchers) == 0 {
+		return true
+	}
+
+	return slices.Contains(p.AllowedDispatchers, name)
+}
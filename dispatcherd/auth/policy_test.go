@@ -0,0 +1,74 @@
+package auth_test
+
+import (
+	"dispatcherd/auth"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyApplyTags(t *testing.T) {
+	policy := &auth.Policy{
+		AllowedTagNames:    []string{"team", "severity", "host"},
+		AllowedTagValues:   map[string][]string{"severity": {"low", "high"}},
+		ForbiddenTagNames:  []string{"host"},
+		ForbiddenTagValues: map[string][]string{"team": {"b"}},
+		ForcedTags:         map[string]string{"source": "team-a"},
+	}
+
+	t.Run("adds forced tags", func(t *testing.T) {
+		tags := map[string]string{"team": "a", "severity": "low"}
+		result, err := policy.ApplyTags(tags)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"team": "a", "severity": "low", "source": "team-a"}, result)
+		// original tags are not modified
+		assert.Len(t, tags, 2)
+	})
+
+	t.Run("forced tags override client tags", func(t *testing.T) {
+		result, err := policy.ApplyTags(map[string]string{"source": "team-b"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "team-a", result["source"])
+	})
+
+	t.Run("rejects tag name not allowed", func(t *testing.T) {
+		_, err := policy.ApplyTags(map[string]string{"cluster": "prod"})
+		assert.ErrorIs(t, err, auth.ErrTagNotAllowed)
+	})
+
+	t.Run("rejects forbidden tag name", func(t *testing.T) {
+		_, err := policy.ApplyTags(map[string]string{"host": "server"})
+		assert.ErrorIs(t, err, auth.ErrTagNotAllowed)
+	})
+
+	t.Run("rejects tag value not allowed", func(t *testing.T) {
+		_, err := policy.ApplyTags(map[string]string{"severity": "critical"})
+		assert.ErrorIs(t, err, auth.ErrTagNotAllowed)
+	})
+
+	t.Run("rejects forbidden tag value", func(t *testing.T) {
+		_, err := policy.ApplyTags(map[string]string{"team": "b"})
+		assert.ErrorIs(t, err, auth.ErrTagNotAllowed)
+	})
+
+	t.Run("nil policy allows everything", func(t *testing.T) {
+		var nilPolicy *auth.Policy
+		tags := map[string]string{"team": "b"}
+		result, err := nilPolicy.ApplyTags(tags)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tags, result)
+	})
+}
+
+func TestPolicyAllowsDispatcher(t *testing.T) {
+	policy := &auth.Policy{AllowedDispatchers: []string{"mail-team-a"}}
+	assert.True(t, policy.AllowsDispatcher("mail-team-a"))
+	assert.False(t, policy.AllowsDispatcher("pager-team-b"))
+
+	var nilPolicy *auth.Policy
+	assert.True(t, nilPolicy.AllowsDispatcher("pager-team-b"))
+	assert.True(t, (&auth.Policy{}).AllowsDispatcher("pager-team-b"))
+}
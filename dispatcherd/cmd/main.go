@@ -2,13 +2,20 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"dispatcherd/auth"
 	"dispatcherd/dispatch"
 	"dispatcherd/logging"
+	"dispatcherd/middleware"
+	"dispatcherd/ratelimit"
 	"dispatcherd/repository"
 	"dispatcherd/service"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/lmittmann/tint"
@@ -20,12 +27,29 @@ const (
 )
 
 type AppConfig struct {
-	ListenAddress             string     `env:"DISPATCHERD_LISTEN_ADDRESS"`
-	LogLevel                  slog.Level `env:"DISPATCHERD_LOG_LEVEL"`
-	Environment               string     `env:"DISPATCHERD_ENVIRONMENT"`
-	CORSOrigin                string     `env:"DISPATCHERD_CORS_ALLOWED_ORIGIN"`
-	RuleDirectory             string     `env:"DISPATCHERD_RULE_DIRECTORY"`
-	DispatcherConfigDirectory string     `env:"DISPATCHERD_DISPATCHER_CONFIG_DIRECTORY"`
+	ListenAddress             string         `env:"DISPATCHERD_LISTEN_ADDRESS"`
+	LogLevel                  slog.Level     `env:"DISPATCHERD_LOG_LEVEL"`
+	Environment               string         `env:"DISPATCHERD_ENVIRONMENT"`
+	CORSOrigin                string         `env:"DISPATCHERD_CORS_ALLOWED_ORIGIN"`
+	TLSCertFile               string         `env:"DISPATCHERD_TLS_CERT_FILE"`
+	TLSKeyFile                string         `env:"DISPATCHERD_TLS_KEY_FILE"`
+	TLSMinVersion             string         `env:"DISPATCHERD_TLS_MIN_VERSION"`
+	TLSClientCAFile           string         `env:"DISPATCHERD_TLS_CLIENT_CA_FILE"`
+	TLSClientCertRequired     bool           `env:"DISPATCHERD_TLS_CLIENT_CERT_REQUIRED"`
+	RuleDirectory             string         `env:"DISPATCHERD_RULE_DIRECTORY"`
+	DispatcherConfigDirectory string         `env:"DISPATCHERD_DISPATCHER_CONFIG_DIRECTORY"`
+	EscalationPolicyDirectory string         `env:"DISPATCHERD_ESCALATION_POLICY_DIRECTORY"`
+	EscalationStateDirectory  string         `env:"DISPATCHERD_ESCALATION_STATE_DIRECTORY"`
+	SilenceStateDirectory     string         `env:"DISPATCHERD_SILENCE_STATE_DIRECTORY"`
+	AuthEnabled               bool           `env:"DISPATCHERD_AUTH_ENABLED"`
+	APIKeyDirectory           string         `env:"DISPATCHERD_API_KEY_DIRECTORY"`
+	RateLimitGlobal           ratelimit.Rate `env:"DISPATCHERD_RATE_LIMIT_GLOBAL"`
+	RateLimitGlobalBurst      int            `env:"DISPATCHERD_RATE_LIMIT_GLOBAL_BURST"`
+	RateLimitClient           ratelimit.Rate `env:"DISPATCHERD_RATE_LIMIT_CLIENT"`
+	RateLimitClientBurst      int            `env:"DISPATCHERD_RATE_LIMIT_CLIENT_BURST"`
+	RateLimitKey              string         `env:"DISPATCHERD_RATE_LIMIT_KEY"`
+	RateLimitKeyTag           string         `env:"DISPATCHERD_RATE_LIMIT_KEY_TAG"`
+	TrustedProxies            []netip.Prefix `env:"DISPATCHERD_TRUSTED_PROXIES"`
 }
 
 func main() {
@@ -35,8 +59,16 @@ func main() {
 		LogLevel:                  slog.LevelDebug,
 		Environment:               EnvProd,
 		CORSOrigin:                "*",
+		TLSMinVersion:             "1.2",
+		TLSClientCertRequired:     true,
 		RuleDirectory:             "/data/rules",
 		DispatcherConfigDirectory: "/data/dispatchers",
+		EscalationPolicyDirectory: "/data/escalation-policies",
+		EscalationStateDirectory:  "/data/escalations",
+		SilenceStateDirectory:     "/data/silences",
+		AuthEnabled:               true,
+		APIKeyDirectory:           "/data/api-keys",
+		RateLimitKey:              string(middleware.RateLimitByAPIKey),
 	}
 	if err := env.Parse(&appConfig); err != nil {
 		fmt.Println(err)
@@ -84,7 +116,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	messageService := service.NewDefaultMessageService(ruleEngine)
+	// load api keys from fs
+	var apiKeys []auth.APIKey
+	if appConfig.AuthEnabled {
+		apiKeyRepo := repository.NewFilesystemAPIKeyRepository(appConfig.APIKeyDirectory)
+		apiKeys, err = apiKeyRepo.ListAPIKeys(context.Background())
+		if err != nil {
+			logger.Error("failed to load api keys", logging.FieldError, err)
+			os.Exit(1)
+		}
+	} else {
+		logger.Warn("authentication is disabled, all endpoints are publicly accessible")
+	}
+
+	escalationRepo := repository.NewFilesystemEscalationRepository(appConfig.EscalationStateDirectory)
+	silenceRepo := repository.NewFilesystemSilenceRepository(appConfig.SilenceStateDirectory)
+	messageService := service.NewDefaultMessageService(ruleEngine, escalationRepo, silenceRepo)
 
 	for _, config := range dispatcherConfigs {
 		if err := messageService.LoadDispatcherConfig(config); err != nil {
@@ -92,10 +139,76 @@ func main() {
 		}
 	}
 
+	// load escalation policies from fs, they are optional
+	escalationPolicyRepo := repository.NewFilesystemEscalationPolicyRepository(appConfig.EscalationPolicyDirectory)
+	escalationPolicies, err := escalationPolicyRepo.ListEscalationPolicies(context.Background())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error("failed to load escalation policies", logging.FieldError, err)
+		os.Exit(1)
+	}
+	for _, policy := range escalationPolicies {
+		if err := messageService.LoadEscalationPolicy(policy); err != nil {
+			logger.Error("failed to load escalation policy "+policy.Name, logging.FieldError, err)
+		}
+	}
+
+	// resume escalations pending before the last shutdown
+	if err := messageService.RestoreEscalations(context.Background()); err != nil {
+		logger.Error("failed to restore pending escalations", logging.FieldError, err)
+		os.Exit(1)
+	}
+
+	if err := messageService.RestoreSilences(context.Background()); err != nil {
+		logger.Error("failed to restore silences", logging.FieldError, err)
+		os.Exit(1)
+	}
+
+	// setup tls
+	var tlsConfig *tls.Config
+	if appConfig.TLSCertFile != "" {
+		tlsConfig, err = NewTLSConfig(TLSOptions{
+			CertFile:           appConfig.TLSCertFile,
+			KeyFile:            appConfig.TLSKeyFile,
+			MinVersion:         appConfig.TLSMinVersion,
+			ClientCAFile:       appConfig.TLSClientCAFile,
+			ClientCertRequired: appConfig.TLSClientCertRequired,
+		})
+		if err != nil {
+			logger.Error("failed to setup TLS", logging.FieldError, err)
+			os.Exit(1)
+		}
+	}
+
+	// setup rate limiting
+	rateLimitKey := middleware.RateLimitKey(appConfig.RateLimitKey)
+	switch rateLimitKey {
+	case middleware.RateLimitByAPIKey, middleware.RateLimitByIP:
+	case middleware.RateLimitByTag:
+		if appConfig.RateLimitKeyTag == "" {
+			logger.Error("rate limiting by tag requires DISPATCHERD_RATE_LIMIT_KEY_TAG")
+			os.Exit(1)
+		}
+	default:
+		logger.Error("unknown rate limit key " + appConfig.RateLimitKey)
+		os.Exit(1)
+	}
+
+	rateLimitOptions := middleware.RateLimitOptions{
+		Global:         ratelimit.Limit{Rate: appConfig.RateLimitGlobal, Burst: appConfig.RateLimitGlobalBurst},
+		Client:         ratelimit.Limit{Rate: appConfig.RateLimitClient, Burst: appConfig.RateLimitClientBurst},
+		KeyBy:          rateLimitKey,
+		KeyTag:         appConfig.RateLimitKeyTag,
+		TrustedProxies: appConfig.TrustedProxies,
+	}
+
 	// start api server
 	serverOptions := ServerOptions{
 		ListenAddress:  appConfig.ListenAddress,
 		CorsOrigin:     appConfig.CORSOrigin,
+		TLSConfig:      tlsConfig,
+		AuthEnabled:    appConfig.AuthEnabled,
+		APIKeys:        apiKeys,
+		RateLimit:      rateLimitOptions,
 		MessageService: messageService,
 	}
 
@@ -103,4 +216,12 @@ func main() {
 
 	server := NewServer(serverOptions)
 	server.Start()
+
+	// deliver queued messages before exiting
+	//nolint:mnd // grace period
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+	if err := messageService.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown message processing gracefully", logging.FieldError, err)
+	}
 }
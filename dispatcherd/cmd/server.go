@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"dispatcherd/auth"
 	"dispatcherd/handler"
 	"dispatcherd/logging"
 	"dispatcherd/middleware"
@@ -22,25 +24,51 @@ import (
 type ServerOptions struct {
 	ListenAddress  string
 	CorsOrigin     string
+	TLSConfig      *tls.Config
+	AuthEnabled    bool
+	APIKeys        []auth.APIKey
+	RateLimit      middleware.RateLimitOptions
 	MessageService service.MessageService
 }
 
 type Server struct {
-	ListenAddress  string
-	router         chi.Router
-	corsOrigin     string
-	messageService service.MessageService
+	ListenAddress       string
+	router              chi.Router
+	corsOrigin          string
+	tlsConfig           *tls.Config
+	authMiddleware      *middleware.AuthMiddleware
+	rateLimitMiddleware *middleware.RateLimitMiddleware
+	messageService      service.MessageService
 }
 
 func NewServer(opts ServerOptions) *Server {
+	var authMiddleware *middleware.AuthMiddleware
+	if opts.AuthEnabled {
+		authMiddleware = middleware.NewAPIKeyAuthMiddleware(opts.APIKeys)
+	}
+
 	return &Server{
-		ListenAddress:  opts.ListenAddress,
-		router:         chi.NewRouter(),
-		corsOrigin:     opts.CorsOrigin,
-		messageService: opts.MessageService,
+		ListenAddress:       opts.ListenAddress,
+		router:              chi.NewRouter(),
+		corsOrigin:          opts.CorsOrigin,
+		tlsConfig:           opts.TLSConfig,
+		authMiddleware:      authMiddleware,
+		rateLimitMiddleware: middleware.NewRateLimitMiddleware(opts.RateLimit),
+		messageService:      opts.MessageService,
 	}
 }
 
+// requireScope protects a route with the given scope, or does nothing if authentication is disabled.
+func (s *Server) requireScope(scope auth.Scope) func(http.Handler) http.Handler {
+	if s.authMiddleware == nil {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return middleware.RequireScope(scope)
+}
+
 func (s *Server) Start() {
 	logger := logging.GetLogger(logging.API)
 
@@ -56,16 +84,35 @@ func (s *Server) Start() {
 	s.router.Use(cors.New(corsOptions).Handler)
 	s.router.Use(middleware.SecurityHeaders())
 	s.router.Use(requestIDMiddleware.OnRequest)
+	if s.authMiddleware != nil {
+		// authenticate before logging requests, so that the client identity is part of the audit log
+		s.router.Use(s.authMiddleware.OnRequest)
+	}
 	s.router.Use(requestLoggerMiddleware.OnRequest)
 
 	s.router.Use(chiMiddleware.AllowContentType("application/json"))
 	s.router.Use(chiMiddleware.Recoverer)
 
 	dispatchHandler := handler.NewDispatchHandler(s.messageService)
+	alertHandler := handler.NewAlertHandler(s.messageService)
+	silenceHandler := handler.NewSilenceHandler(s.messageService)
 
 	// register public routes
 	s.router.Get("/health", handler.Make(handler.HandleHealth))
-	s.router.Post("/message", handler.Make(dispatchHandler.HandlePost))
+
+	// register protected routes
+	s.router.With(s.requireScope(auth.ScopeMessageSend), s.rateLimitMiddleware.OnRequest).
+		Post("/message", handler.Make(dispatchHandler.HandlePost))
+	s.router.With(s.requireScope(auth.ScopeMessageAck)).
+		Post("/message/{id}/ack", handler.Make(dispatchHandler.HandleAck))
+	s.router.With(s.requireScope(auth.ScopeRulesRead)).
+		Post("/rules/evaluate", handler.Make(dispatchHandler.HandleEvaluate))
+	s.router.With(s.requireScope(auth.ScopeAlertsRead)).
+		Get("/alerts", handler.Make(alertHandler.HandleList))
+	s.router.With(s.requireScope(auth.ScopeSilencesRead)).
+		Get("/silences", handler.Make(silenceHandler.HandleList))
+	s.router.With(s.requireScope(auth.ScopeSilencesWrite)).
+		Post("/silences", handler.Make(silenceHandler.HandlePost))
 
 	// setup default handlers
 	s.router.NotFound(func(w http.ResponseWriter, r *http.Request) {
@@ -78,8 +125,9 @@ func (s *Server) Start() {
 
 	// setup graceful shutdown
 	server := &http.Server{
-		Addr:    s.ListenAddress,
-		Handler: s.router,
+		Addr:      s.ListenAddress,
+		Handler:   s.router,
+		TLSConfig: s.tlsConfig,
 		//nolint:mnd // just a default to prevent slow loris
 		ReadHeaderTimeout: 5 * time.Second,
 	}
@@ -112,8 +160,15 @@ func (s *Server) Start() {
 	}()
 
 	// start listening for connections
-	logger.Info("listening on " + s.ListenAddress)
-	err := server.ListenAndServe()
+	var err error
+	if s.tlsConfig != nil {
+		logger.Info("listening with TLS on " + s.ListenAddress)
+		// certificates are provided by the TLS config
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		logger.Info("listening on " + s.ListenAddress)
+		err = server.ListenAndServe()
+	}
 
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("failed to start server on "+s.ListenAddress, logging.FieldError, err)
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"dispatcherd/logging"
+	"dispatcherd/tlsutil"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TLSOptions struct {
+	CertFile           string
+	KeyFile            string
+	MinVersion         string
+	ClientCAFile       string
+	ClientCertRequired bool
+}
+
+// TLS 1.0 and 1.1 are deprecated by RFC 8996 and not offered.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	minVersion, ok := tlsVersions[opts.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum TLS version '%s'", opts.MinVersion)
+	}
+
+	reloader, err := newCertificateReloader(opts.CertFile, opts.KeyFile, opts.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:exhaustruct // pkg defaults are fine
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if opts.ClientCAFile != "" {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if opts.ClientCertRequired {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		config.ClientCAs = reloader.ClientCAs()
+
+		// hand out a fresh config per connection, so that a rotated client CA is picked up
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig := config.Clone()
+			clientConfig.GetConfigForClient = nil
+			clientConfig.ClientCAs = reloader.ClientCAs()
+			return clientConfig, nil
+		}
+	}
+
+	return config, nil
+}
+
+// certificates are checked for changes at most this often, instead of on every handshake
+var certificateCheckInterval = 10 * time.Second
+
+// certificateReloader reloads the server certificate and the client CA whenever their files change on disk.
+type certificateReloader struct {
+	logger       *slog.Logger
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	certificate atomic.Pointer[tls.Certificate]
+	clientCAs   atomic.Pointer[x509.CertPool]
+	// unix nanoseconds of the next check
+	nextCheck atomic.Int64
+
+	// guards the checks, handshakes running meanwhile keep using the loaded files
+	mu              sync.Mutex
+	certModTime     time.Time
+	keyModTime      time.Time
+	clientCAModTime time.Time
+}
+
+func newCertificateReloader(certFile string, keyFile string, clientCAFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{
+		logger:       logging.GetLogger(logging.API),
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+
+	if err := reloader.loadCertificate(); err != nil {
+		return nil, fmt.Errorf("loading certificate: %w", err)
+	}
+
+	if clientCAFile != "" {
+		if err := reloader.loadClientCAs(); err != nil {
+			return nil, fmt.Errorf("loading client CA: %w", err)
+		}
+	}
+
+	reloader.nextCheck.Store(time.Now().Add(certificateCheckInterval).UnixNano())
+
+	return reloader, nil
+}
+
+func (c *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.checkFiles()
+	return c.certificate.Load(), nil
+}
+
+func (c *certificateReloader) ClientCAs() *x509.CertPool {
+	c.checkFiles()
+	return c.clientCAs.Load()
+}
+
+// checkFiles reloads the files which changed on disk, if the check interval passed.
+func (c *certificateReloader) checkFiles() {
+	now := time.Now()
+	if now.UnixNano() < c.nextCheck.Load() || !c.mu.TryLock() {
+		// not due yet, or another handshake is checking already
+		return
+	}
+	defer c.mu.Unlock()
+
+	c.nextCheck.Store(now.Add(certificateCheckInterval).UnixNano())
+
+	if fileChanged(c.certFile, c.certModTime) || fileChanged(c.keyFile, c.keyModTime) {
+		if err := c.loadCertificate(); err != nil {
+			// keep serving the previous certificate, the new one might not be completely written yet
+			c.logger.Error("failed to reload certificate", logging.FieldError, err)
+		} else {
+			c.logger.Info("reloaded certificate from " + c.certFile)
+		}
+	}
+
+	if c.clientCAFile != "" && fileChanged(c.clientCAFile, c.clientCAModTime) {
+		if err := c.loadClientCAs(); err != nil {
+			c.logger.Error("failed to reload client CA", logging.FieldError, err)
+		} else {
+			c.logger.Info("reloaded client CA from " + c.clientCAFile)
+		}
+	}
+}
+
+func (c *certificateReloader) loadCertificate() error {
+	certModTime, err := modTime(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyModTime, err := modTime(c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	// remember the attempt even if it fails, to only retry once the files change again
+	c.certModTime = certModTime
+	c.keyModTime = keyModTime
+
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.certificate.Store(&certificate)
+
+	return nil
+}
+
+func (c *certificateReloader) loadClientCAs() error {
+	caModTime, err := modTime(c.clientCAFile)
+	if err != nil {
+		return err
+	}
+	c.clientCAModTime = caModTime
+
+	pool, err := tlsutil.LoadCertPool(c.clientCAFile)
+	if err != nil {
+		return err
+	}
+	c.clientCAs.Store(pool)
+
+	return nil
+}
+
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
+func fileChanged(path string, lastModTime time.Time) bool {
+	current, err := modTime(path)
+	if err != nil {
+		// file is probably being replaced, keep the current one
+		return false
+	}
+
+	return !current.Equal(lastModTime)
+}
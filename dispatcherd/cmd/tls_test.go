@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dispatcherd/tlsutil"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func createTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestCertificate(t *testing.T, dir string, name string, cert *testCertificate) (string, string) {
+	t.Helper()
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, cert.certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, cert.keyPEM, 0600))
+
+	return certFile, keyFile
+}
+
+func TestNewTLSConfigInvalid(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "server", createTestCertificate(t, "server", nil))
+
+	t.Run("unsupported version", func(t *testing.T) {
+		_, err := NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "2.0"})
+		assert.Error(t, err)
+	})
+
+	t.Run("deprecated version", func(t *testing.T) {
+		_, err := NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.1"})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing certificate", func(t *testing.T) {
+		_, err := NewTLSConfig(TLSOptions{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile, MinVersion: "1.2"})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid client CA", func(t *testing.T) {
+		caFile := filepath.Join(dir, "ca.crt")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+
+		_, err := NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2", ClientCAFile: caFile})
+		assert.ErrorIs(t, err, tlsutil.ErrNoCertificatesInCAFile)
+	})
+}
+
+func TestCertificateReload(t *testing.T) {
+	// check the files on every handshake
+	checkInterval := certificateCheckInterval
+	certificateCheckInterval = 0
+	t.Cleanup(func() {
+		certificateCheckInterval = checkInterval
+	})
+
+	dir := t.TempDir()
+	first := createTestCertificate(t, "first", nil)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", first)
+
+	config, err := NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+
+	served, err := config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.Raw, served.Certificate[0])
+
+	// rotate the certificate on disk
+	second := createTestCertificate(t, "second", nil)
+	writeTestCertificate(t, dir, "server", second)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	served, err = config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.cert.Raw, served.Certificate[0])
+
+	// a broken certificate keeps the previous one in place
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0600))
+	later := future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	served, err = config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.cert.Raw, served.Certificate[0])
+}
+
+func TestCertificateReloadInterval(t *testing.T) {
+	dir := t.TempDir()
+	first := createTestCertificate(t, "first", nil)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", first)
+
+	config, err := NewTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	require.NoError(t, err)
+
+	// a rotated certificate is only picked up by the next check
+	writeTestCertificate(t, dir, "server", createTestCertificate(t, "second", nil))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	served, err := config.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.Raw, served.Certificate[0])
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := createTestCertificate(t, "ca", nil)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.certPEM, 0600))
+
+	server := createTestCertificate(t, "localhost", ca)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", server)
+
+	config, err := NewTLSConfig(TLSOptions{
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		MinVersion:         "1.2",
+		ClientCAFile:       caFile,
+		ClientCertRequired: true,
+	})
+	require.NoError(t, err)
+
+	var commonName string
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonName = r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}))
+	testServer.TLS = config
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	newClient := func(cert *testCertificate) *http.Client {
+		tlsConfig := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+		if cert != nil {
+			tlsConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{cert.cert.Raw}, PrivateKey: cert.key}}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+
+	t.Run("accepts client certificate signed by CA", func(t *testing.T) {
+		res, err := newClient(createTestCertificate(t, "producer", ca)).Get(testServer.URL)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "producer", commonName)
+	})
+
+	t.Run("rejects missing client certificate", func(t *testing.T) {
+		_, err := newClient(nil).Get(testServer.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects client certificate of other CA", func(t *testing.T) {
+		otherCA := createTestCertificate(t, "other-ca", nil)
+		_, err := newClient(createTestCertificate(t, "producer", otherCA)).Get(testServer.URL)
+		assert.Error(t, err)
+	})
+}
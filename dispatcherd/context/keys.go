@@ -9,6 +9,8 @@ type Key string
 const (
 	KeyRequestID Key = "request-id"
 	KeyMessageID Key = "message-id"
+	KeyClientID  Key = "client-id"
+	KeyIdentity  Key = "identity"
 )
 
 func RequestID(ctx context.Context) string {
@@ -18,3 +20,11 @@ func RequestID(ctx context.Context) string {
 
 	return ""
 }
+
+func ClientID(ctx context.Context) string {
+	if val, ok := ctx.Value(KeyClientID).(string); ok {
+		return val
+	}
+
+	return ""
+}
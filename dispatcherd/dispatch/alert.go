@@ -0,0 +1,18 @@
+package dispatch
+
+import "time"
+
+// Alert is an open alert, created by a firing message with a key.
+type Alert struct {
+	Key       string            `json:"key"`
+	Title     string            `json:"title"`
+	Message   string            `json:"message"`
+	Tags      map[string]string `json:"tags"`
+	MessageID string            `json:"messageId"`
+	// client which fired the alert, empty without authentication
+	Owner string `json:"owner,omitempty"`
+	// dispatchers which received the firing message, and are notified once the alert is resolved
+	Dispatchers []string  `json:"dispatchers"`
+	FiredAt     time.Time `json:"firedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
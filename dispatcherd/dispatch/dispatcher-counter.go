@@ -1,13 +1,16 @@
 package dispatch
 
-import "context"
+import (
+	"context"
+	"sync/atomic"
+)
 
 type CounterDispatcher struct {
-	CallsCount int
+	callsCount atomic.Int64
 }
 
 func (c *CounterDispatcher) Dispatch(ctx context.Context, msg *Message) error {
-	c.CallsCount++
+	c.callsCount.Add(1)
 	return nil
 }
 
@@ -19,6 +22,11 @@ func (c *CounterDispatcher) SetConfig(config map[string]interface{}) {
 	// nothing to do
 }
 
+// CallsCount returns how many messages were dispatched.
+func (c *CounterDispatcher) CallsCount() int {
+	return int(c.callsCount.Load())
+}
+
 func NewCounterDispatcher() *CounterDispatcher {
 	return &CounterDispatcher{}
 }
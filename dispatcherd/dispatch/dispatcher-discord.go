@@ -0,0 +1,210 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// limits of a Discord embed, in characters
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordFieldLimit       = 25
+	discordFieldNameLimit   = 256
+	discordFieldValueLimit  = 1024
+	discordEmbedLimit       = 6000
+	discordMaxAttempts      = 3
+)
+
+const (
+	discordColorCritical = 0xE74C3C
+	discordColorWarning  = 0xF1C40F
+	discordColorOK       = 0x2ECC71
+)
+
+type discordConfig struct {
+	webhookURL  string
+	username    string
+	avatarURL   string
+	severityTag string
+}
+
+// DiscordDispatcher posts messages as embeds to a Discord webhook.
+type DiscordDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config discordConfig
+}
+
+type discordPayload struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+func (e discordEmbed) length() int {
+	length := len([]rune(e.Title)) + len([]rune(e.Description))
+	for _, field := range e.Fields {
+		length += len([]rune(field.Name)) + len([]rune(field.Value))
+	}
+	return length
+}
+
+func (d *DiscordDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	embeds := d.embeds(msg)
+	for _, embed := range embeds {
+		payload := discordPayload{
+			Username:  d.config.username,
+			AvatarURL: d.config.avatarURL,
+			Embeds:    []discordEmbed{embed},
+		}
+
+		if err := d.post(ctx, payload); err != nil {
+			return err
+		}
+	}
+
+	d.logger.DebugContext(ctx, fmt.Sprintf("posted message to discord in %d parts", len(embeds)))
+
+	return nil
+}
+
+// embeds renders the message as one embed per request. Long bodies are split over several embeds, the tags are added
+// as fields to the last one, or to an embed of their own if they do not fit.
+func (d *DiscordDispatcher) embeds(msg *Message) []discordEmbed {
+	color := d.color(msg)
+
+	var embeds []discordEmbed
+	for _, chunk := range splitText(msg.Message, discordDescriptionLimit) {
+		embeds = append(embeds, discordEmbed{Description: chunk, Color: color})
+	}
+	embeds[0].Title = truncate(msg.Title, discordTitleLimit)
+
+	fields := make([]discordField, 0, len(msg.Tags))
+	for _, name := range sortedTagNames(msg.Tags) {
+		if len(fields) == discordFieldLimit {
+			break
+		}
+		fields = append(fields, discordField{
+			Name:   truncate(name, discordFieldNameLimit),
+			Value:  truncate(msg.Tags[name], discordFieldValueLimit),
+			Inline: true,
+		})
+	}
+
+	if len(fields) > 0 {
+		last := &embeds[len(embeds)-1]
+		if last.length()+(discordEmbed{Fields: fields}).length() <= discordEmbedLimit {
+			last.Fields = fields
+		} else {
+			embeds = append(embeds, discordEmbed{Color: color, Fields: fields})
+		}
+	}
+
+	return embeds
+}
+
+func (d *DiscordDispatcher) color(msg *Message) int {
+	switch severityOf(msg, d.config.severityTag) {
+	case severityCritical:
+		return discordColorCritical
+	case severityWarning:
+		return discordColorWarning
+	case severityOK:
+		return discordColorOK
+	default:
+		return 0
+	}
+}
+
+// post sends the payload, waiting and retrying as long as Discord asks to when it is rate limited.
+func (d *DiscordDispatcher) post(ctx context.Context, payload discordPayload) error {
+	for attempt := 1; ; attempt++ {
+		_, err := postJSON(ctx, d.client, d.config.webhookURL, payload, nil)
+
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests || attempt == discordMaxAttempts {
+			return err
+		}
+
+		retryAfter := discordRetryAfter(statusErr)
+		d.logger.WarnContext(ctx, fmt.Sprintf("rate limited by discord, retrying in %s", retryAfter))
+
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// discordRetryAfter reads the delay of a rate limited request, from the body or else the Retry-After header.
+func discordRetryAfter(err *HTTPStatusError) time.Duration {
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(err.Body, &body) == nil && body.RetryAfter > 0 {
+		return time.Duration(body.RetryAfter * float64(time.Second))
+	}
+
+	if seconds, parseErr := strconv.ParseFloat(err.Header.Get("Retry-After"), 64); parseErr == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	return time.Second
+}
+
+func (d *DiscordDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"webhookUrl":  "required,http_url",
+		"username":    "omitempty",
+		"avatarUrl":   "omitempty,http_url",
+		"severityTag": "omitempty",
+	}
+}
+
+func (d *DiscordDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = discordConfig{
+		webhookURL:  config["webhookUrl"].(string),
+		severityTag: defaultSeverityTag,
+	}
+
+	if username, ok := config["username"].(string); ok {
+		d.config.username = username
+	}
+	if avatarURL, ok := config["avatarUrl"].(string); ok {
+		d.config.avatarURL = avatarURL
+	}
+	if severityTag, ok := config["severityTag"].(string); ok && severityTag != "" {
+		d.config.severityTag = severityTag
+	}
+}
+
+func NewDiscordDispatcher() *DiscordDispatcher {
+	return &DiscordDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
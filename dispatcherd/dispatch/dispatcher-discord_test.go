@@ -0,0 +1,132 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+	Fields      []struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	} `json:"fields"`
+}
+
+type discordRecorder struct {
+	mu          sync.Mutex
+	rateLimited int
+	embeds      []discordEmbed
+}
+
+func (rec *discordRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.rateLimited > 0 {
+		rec.rateLimited--
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.05, "global": false}`))
+		return
+	}
+
+	var payload struct {
+		Username string         `json:"username"`
+		Embeds   []discordEmbed `json:"embeds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rec.embeds = append(rec.embeds, payload.Embeds...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestDiscordDispatcher(t *testing.T) {
+	recorder := &discordRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	dispatcher := dispatch.NewDiscordDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{"webhookUrl": server.URL, "username": "dispatcherd"})
+
+	msg := dispatch.NewMessage("Deploy finished", "all good", map[string]string{"severity": "info", "env": "prod"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	require.Len(t, recorder.embeds, 1)
+	embed := recorder.embeds[0]
+	assert.Equal(t, "Deploy finished", embed.Title)
+	assert.Equal(t, "all good", embed.Description)
+	assert.Equal(t, 0x2ECC71, embed.Color)
+	require.Len(t, embed.Fields, 2)
+	assert.Equal(t, "env", embed.Fields[0].Name)
+	assert.Equal(t, "prod", embed.Fields[0].Value)
+}
+
+func TestDiscordDispatcherSplitsLongMessages(t *testing.T) {
+	recorder := &discordRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	dispatcher := dispatch.NewDiscordDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{"webhookUrl": server.URL})
+
+	line := strings.Repeat("x", 99) + "\n"
+	body := strings.Repeat(line, 50)
+	msg := dispatch.NewMessage(strings.Repeat("t", 300), body, map[string]string{"host": "db1"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	require.Len(t, recorder.embeds, 2)
+	assert.Len(t, []rune(recorder.embeds[0].Title), 256)
+	assert.Empty(t, recorder.embeds[1].Title)
+	assert.LessOrEqual(t, len(recorder.embeds[0].Description), 4096)
+	assert.True(t, body == recorder.embeds[0].Description+"\n"+recorder.embeds[1].Description)
+	assert.Empty(t, recorder.embeds[0].Fields)
+	assert.Len(t, recorder.embeds[1].Fields, 1)
+}
+
+func TestDiscordDispatcherRateLimited(t *testing.T) {
+	t.Run("retries after the requested delay", func(t *testing.T) {
+		recorder := &discordRecorder{rateLimited: 1}
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		dispatcher := dispatch.NewDiscordDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{"webhookUrl": server.URL})
+
+		start := time.Now()
+		require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil)))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Len(t, recorder.embeds, 1)
+	})
+
+	t.Run("gives up eventually", func(t *testing.T) {
+		recorder := &discordRecorder{rateLimited: 10}
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		dispatcher := dispatch.NewDiscordDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{"webhookUrl": server.URL})
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+
+		var statusErr *dispatch.HTTPStatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.Equal(t, 7, recorder.rateLimited)
+	})
+}
@@ -0,0 +1,272 @@
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"dispatcherd/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultExecTimeout    = 30 * time.Second
+	defaultExecRetryDelay = time.Second
+	// messages of outlets without a queue are dispatched while the client waits, so retrying is kept short
+	defaultExecMaxRetryTime = time.Minute
+	// the output of a command is kept up to this size for the logs
+	execOutputLimit = 64 * 1024
+	// how long to wait for the output of a killed command, which may have started processes keeping the pipes open
+	execWaitDelay = time.Second
+)
+
+type execConfig struct {
+	command      string
+	args         []string
+	workDir      string
+	env          map[string]string
+	timeout      time.Duration
+	retries      int
+	retryDelay   time.Duration
+	maxRetryTime time.Duration
+}
+
+// ExecDispatcher runs a local command for every message. The message is passed as JSON on stdin and as environment
+// variables, a command exiting with a non-zero code failed to deliver it.
+type ExecDispatcher struct {
+	logger *slog.Logger
+	config execConfig
+}
+
+// ExecError is returned if the command did not exit successfully.
+type ExecError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *ExecError) Error() string {
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		return fmt.Sprintf("command '%s' failed with exit code %d: %s", e.Command, e.ExitCode, stderr)
+	}
+	return fmt.Sprintf("command '%s' failed with exit code %d: %v", e.Command, e.ExitCode, e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+func (d *ExecDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	input, err := json.Marshal(newMessageRecord(msg, time.Now()))
+	if err != nil {
+		return err
+	}
+
+	// no retry is started after the deadline, which bounds how long a message is held up by a failing command
+	deadline := time.Now().Add(d.config.maxRetryTime)
+	for attempt := 0; ; attempt++ {
+		err := d.run(ctx, msg, input)
+
+		// only failures of the command are retried, not commands which could not be started at all
+		var execErr *ExecError
+		if err == nil || !errors.As(err, &execErr) || attempt == d.config.retries {
+			return err
+		}
+		if time.Now().Add(d.config.retryDelay).After(deadline) {
+			return fmt.Errorf("giving up retrying after %s: %w", d.config.maxRetryTime, err)
+		}
+
+		d.logger.WarnContext(ctx, fmt.Sprintf("retrying command in %s", d.config.retryDelay), logging.FieldError, err)
+
+		timer := time.NewTimer(d.config.retryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+func (d *ExecDispatcher) run(ctx context.Context, msg *Message, input []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, d.config.timeout)
+	defer cancel()
+
+	//nolint:gosec // the command is configured by the operator
+	cmd := exec.CommandContext(ctx, d.config.command, d.config.args...)
+	cmd.Dir = d.config.workDir
+	cmd.Env = d.environment(msg)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.WaitDelay = execWaitDelay
+
+	stdout := &limitedBuffer{limit: execOutputLimit}
+	stderr := &limitedBuffer{limit: execOutputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+
+	if stdout.Len() > 0 || stderr.Len() > 0 {
+		d.logger.InfoContext(ctx, "output of command "+d.config.command, "stdout", stdout.String(),
+			"stderr", stderr.String())
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("running command '%s': %w", d.config.command, err)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s: %w", d.config.timeout, err)
+	}
+
+	return &ExecError{
+		Command:  d.config.command,
+		ExitCode: exitErr.ExitCode(),
+		Stderr:   stderr.String(),
+		Err:      err,
+	}
+}
+
+// environment passes the message as DISPATCHERD_* variables. Apart from PATH, the environment of dispatcherd is not
+// passed on, so that its secrets do not leak to the command.
+func (d *ExecDispatcher) environment(msg *Message) []string {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"DISPATCHERD_ID=" + msg.ID,
+		"DISPATCHERD_TITLE=" + msg.Title,
+		"DISPATCHERD_MESSAGE=" + msg.Message,
+		"DISPATCHERD_KEY=" + msg.Key,
+		"DISPATCHERD_STATUS=" + string(msg.Status),
+	}
+
+	for _, name := range sortedTagNames(msg.Tags) {
+		env = append(env, "DISPATCHERD_TAG_"+envName(name)+"="+msg.Tags[name])
+	}
+
+	for _, name := range sortedTagNames(d.config.env) {
+		env = append(env, name+"="+d.config.env[name])
+	}
+
+	return env
+}
+
+// envName turns a tag name into the part of an environment variable name, e.g. "http-status" into "HTTP_STATUS".
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// limitedBuffer keeps the first bytes written to it and discards the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		b.Buffer.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+func (d *ExecDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"command":      "required",
+		"args":         "omitempty",
+		"workDir":      "omitempty,dir",
+		"env":          "omitempty",
+		"timeout":      "omitempty",
+		"retries":      "omitempty,min=0,max=10",
+		"retryDelay":   "omitempty",
+		"maxRetryTime": "omitempty",
+	}
+}
+
+func (d *ExecDispatcher) ValidateConfig(config map[string]interface{}) error {
+	command, _ := config["command"].(string)
+	// like the command run in workDir, a relative path is resolved against it
+	if workDir, ok := config["workDir"].(string); ok && workDir != "" &&
+		strings.ContainsRune(command, filepath.Separator) && !filepath.IsAbs(command) {
+		command = filepath.Join(workDir, command)
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return err
+	}
+
+	for _, key := range []string{"timeout", "retryDelay", "maxRetryTime"} {
+		if value, ok := config[key].(string); ok && value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			if parsed <= 0 {
+				return fmt.Errorf("%s must be positive", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *ExecDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = execConfig{
+		command:      config["command"].(string),
+		env:          map[string]string{},
+		timeout:      defaultExecTimeout,
+		retryDelay:   defaultExecRetryDelay,
+		maxRetryTime: defaultExecMaxRetryTime,
+	}
+
+	if args, ok := config["args"].([]interface{}); ok {
+		for _, arg := range args {
+			d.config.args = append(d.config.args, fmt.Sprint(arg))
+		}
+	}
+	if workDir, ok := config["workDir"].(string); ok {
+		d.config.workDir = workDir
+	}
+	if env, ok := config["env"].(map[string]interface{}); ok {
+		for name, value := range env {
+			d.config.env[name] = fmt.Sprint(value)
+		}
+	}
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		d.config.timeout, _ = time.ParseDuration(timeout)
+	}
+	if retries, ok := config["retries"].(float64); ok {
+		d.config.retries = int(math.Round(retries))
+	}
+	if retryDelay, ok := config["retryDelay"].(string); ok && retryDelay != "" {
+		d.config.retryDelay, _ = time.ParseDuration(retryDelay)
+	}
+	if maxRetryTime, ok := config["maxRetryTime"].(string); ok && maxRetryTime != "" {
+		d.config.maxRetryTime, _ = time.ParseDuration(maxRetryTime)
+	}
+}
+
+func NewExecDispatcher() *ExecDispatcher {
+	return &ExecDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+	}
+}
@@ -0,0 +1,137 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupExecDispatcher(t *testing.T, script string, config map[string]interface{}) *dispatch.ExecDispatcher {
+	t.Helper()
+
+	config["command"] = "/bin/sh"
+	config["args"] = []interface{}{"-c", script}
+
+	dispatcher := dispatch.NewExecDispatcher()
+	require.NoError(t, dispatcher.ValidateConfig(config))
+	dispatcher.SetConfig(config)
+
+	return dispatcher
+}
+
+func TestExecDispatcher(t *testing.T) {
+	dir := t.TempDir()
+	dispatcher := setupExecDispatcher(t,
+		`cat > stdin.json && env | grep -e ^DISPATCHERD_ -e ^TARGET= | sort > env.txt && echo delivered`,
+		map[string]interface{}{"workDir": dir, "env": map[string]interface{}{"TARGET": "ops"}})
+
+	msg := dispatch.NewMessage("Disk full", "/var is at 98%", map[string]string{"host": "db1", "http-status": "507"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	stdin, err := os.ReadFile(filepath.Join(dir, "stdin.json"))
+	require.NoError(t, err)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(stdin, &record))
+	assert.Equal(t, msg.ID, record["id"])
+	assert.Equal(t, "Disk full", record["title"])
+	assert.Equal(t, "/var is at 98%", record["message"])
+	assert.Equal(t, map[string]interface{}{"host": "db1", "http-status": "507"}, record["tags"])
+
+	env, err := os.ReadFile(filepath.Join(dir, "env.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"DISPATCHERD_ID=" + msg.ID,
+		"DISPATCHERD_KEY=",
+		"DISPATCHERD_MESSAGE=/var is at 98%",
+		"DISPATCHERD_STATUS=",
+		"DISPATCHERD_TAG_HOST=db1",
+		"DISPATCHERD_TAG_HTTP_STATUS=507",
+		"DISPATCHERD_TITLE=Disk full",
+		"TARGET=ops",
+	}, strings.Split(strings.TrimSpace(string(env)), "\n"))
+}
+
+func TestExecDispatcherFailed(t *testing.T) {
+	t.Run("non-zero exit code", func(t *testing.T) {
+		dispatcher := setupExecDispatcher(t, `echo "receiver unavailable" >&2; exit 3`, map[string]interface{}{})
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+
+		var execErr *dispatch.ExecError
+		require.ErrorAs(t, err, &execErr)
+		assert.Equal(t, 3, execErr.ExitCode)
+		assert.ErrorContains(t, err, "receiver unavailable")
+	})
+
+	t.Run("retries failed commands", func(t *testing.T) {
+		dir := t.TempDir()
+		// fails on the first attempt only
+		dispatcher := setupExecDispatcher(t, `[ -f attempted ] || { touch attempted; exit 1; }`, map[string]interface{}{
+			"workDir":    dir,
+			"retries":    float64(2),
+			"retryDelay": "10ms",
+		})
+
+		assert.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil)))
+	})
+
+	t.Run("stops retrying after max retry time", func(t *testing.T) {
+		dir := t.TempDir()
+		dispatcher := setupExecDispatcher(t, `echo attempt >> attempts.txt; exit 1`, map[string]interface{}{
+			"workDir":      dir,
+			"retries":      float64(10),
+			"retryDelay":   "50ms",
+			"maxRetryTime": "120ms",
+		})
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+		assert.ErrorContains(t, err, "giving up retrying after 120ms")
+
+		attempts, err := os.ReadFile(filepath.Join(dir, "attempts.txt"))
+		require.NoError(t, err)
+		assert.Less(t, strings.Count(string(attempts), "attempt"), 4)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		dispatcher := setupExecDispatcher(t, `sleep 10`, map[string]interface{}{"timeout": "100ms"})
+
+		start := time.Now()
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+
+		var execErr *dispatch.ExecError
+		require.ErrorAs(t, err, &execErr)
+		assert.ErrorContains(t, err, "timed out")
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		dispatcher := dispatch.NewExecDispatcher()
+
+		assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"command": "/does/not/exist"}))
+		assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"command": "/bin/sh", "timeout": "soon"}))
+		assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"command": "/bin/sh", "maxRetryTime": "0s"}))
+	})
+}
+
+func TestExecDispatcherRelativeCommand(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notify.sh"), []byte("#!/bin/sh\ncat > stdin.json\n"), 0755))
+	config := map[string]interface{}{"command": "./notify.sh", "workDir": dir}
+
+	dispatcher := dispatch.NewExecDispatcher()
+	require.NoError(t, dispatcher.ValidateConfig(config))
+	dispatcher.SetConfig(config)
+
+	require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil)))
+	assert.FileExists(t, filepath.Join(dir, "stdin.json"))
+
+	assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"command": "./missing.sh", "workDir": dir}))
+}
@@ -0,0 +1,371 @@
+package dispatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"dispatcherd/logging"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	fileSyncAlways = "always"
+	fileSyncNever  = "never"
+
+	rotatedTimeFormat = "20060102T150405.000000000"
+)
+
+type fileConfig struct {
+	path           string
+	template       *template.Template
+	maxBytes       int64
+	rotateInterval time.Duration
+	maxBackups     int
+	compress       bool
+	sync           string
+}
+
+// FileDispatcher appends messages as JSON lines, or lines rendered by a template, to a file. The file is rotated once
+// it exceeds its maximum size or its rotation interval has passed.
+type FileDispatcher struct {
+	logger *slog.Logger
+	config fileConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// rotated files are compressed and pruned in the background, one rotation after the other
+	cleanup   sync.Mutex
+	cleanupWg sync.WaitGroup
+}
+
+func (d *FileDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	line, err := d.render(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.open(); err != nil {
+		return err
+	}
+
+	if d.rotationDue(int64(len(line)), time.Now()) {
+		if err := d.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := d.file.Write(line)
+	d.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if d.config.sync == fileSyncAlways {
+		return d.file.Sync()
+	}
+
+	return nil
+}
+
+func (d *FileDispatcher) render(msg *Message, now time.Time) ([]byte, error) {
+	entry := newMessageRecord(msg, now)
+
+	if d.config.template == nil {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		return append(line, '\n'), nil
+	}
+
+	var line bytes.Buffer
+	if err := d.config.template.Execute(&line, entry); err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(line.Bytes(), []byte("\n")) {
+		line.WriteByte('\n')
+	}
+
+	return line.Bytes(), nil
+}
+
+// open opens the file for appending if it is not open yet. Must be called with the lock held.
+func (d *FileDispatcher) open() error {
+	if d.file != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.config.path), 0750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(d.config.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	d.file = file
+	d.size = info.Size()
+	// an existing file was started no later than it was last written to
+	d.openedAt = time.Now()
+	if info.Size() > 0 {
+		d.openedAt = info.ModTime()
+	}
+
+	return nil
+}
+
+// rotationDue reports whether the file has to be rotated before writing the next line. Time based rotation happens
+// at multiples of the interval, e.g. at midnight UTC for an interval of 24h.
+func (d *FileDispatcher) rotationDue(lineSize int64, now time.Time) bool {
+	if d.size == 0 {
+		return false
+	}
+
+	if d.config.maxBytes > 0 && d.size+lineSize > d.config.maxBytes {
+		return true
+	}
+
+	interval := d.config.rotateInterval
+	return interval > 0 && !now.Truncate(interval).Equal(d.openedAt.Truncate(interval))
+}
+
+// rotate moves the current file aside and opens a new one. Must be called with the lock held.
+func (d *FileDispatcher) rotate() error {
+	if err := d.closeFile(); err != nil {
+		return err
+	}
+
+	rotated := d.rotatedName(time.Now().UTC())
+	if err := os.Rename(d.config.path, rotated); err != nil {
+		return err
+	}
+
+	d.cleanupWg.Add(1)
+	go func() {
+		defer d.cleanupWg.Done()
+		d.cleanupRotated(rotated)
+	}()
+
+	return d.open()
+}
+
+// rotatedName names a rotated file after the time of its rotation, which never replaces an earlier rotated file.
+func (d *FileDispatcher) rotatedName(now time.Time) string {
+	extension := filepath.Ext(d.config.path)
+	base := strings.TrimSuffix(d.config.path, extension)
+
+	for {
+		name := fmt.Sprintf("%s-%s%s", base, now.Format(rotatedTimeFormat), extension)
+		_, err := os.Stat(name)
+		_, gzErr := os.Stat(name + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return name
+		}
+		now = now.Add(time.Nanosecond)
+	}
+}
+
+// cleanupRotated compresses the rotated file and removes the oldest rotated files beyond the retention count.
+func (d *FileDispatcher) cleanupRotated(rotated string) {
+	d.cleanup.Lock()
+	defer d.cleanup.Unlock()
+
+	if d.config.compress {
+		if err := compressFile(rotated); err != nil {
+			d.logger.Error("failed to compress rotated file", logging.FieldError, err, "file", rotated)
+		}
+	}
+
+	if d.config.maxBackups <= 0 {
+		return
+	}
+
+	extension := filepath.Ext(d.config.path)
+	base := strings.TrimSuffix(d.config.path, extension)
+	pattern := base + "-*" + extension
+	backups, err := filepath.Glob(pattern)
+	if err != nil {
+		d.logger.Error("failed to list rotated files", logging.FieldError, err)
+		return
+	}
+	compressed, _ := filepath.Glob(pattern + ".gz")
+	backups = append(backups, compressed...)
+	// the pattern matches other files next to the log as well, like "alerts-1h.log" for "alerts.log"
+	backups = slices.DeleteFunc(backups, func(name string) bool {
+		return !isRotatedName(name, base, extension)
+	})
+
+	// the timestamps in the names sort rotated files from the oldest to the newest
+	slices.SortFunc(backups, func(a, b string) int {
+		return strings.Compare(strings.TrimSuffix(a, ".gz"), strings.TrimSuffix(b, ".gz"))
+	})
+	// without an extension, the first pattern matches the compressed files as well
+	backups = slices.Compact(backups)
+
+	for len(backups) > d.config.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			d.logger.Error("failed to remove rotated file", logging.FieldError, err, "file", backups[0])
+		}
+		backups = backups[1:]
+	}
+}
+
+// isRotatedName tells whether the name is one of rotatedName for the base and extension, optionally compressed.
+func isRotatedName(name, base, extension string) bool {
+	timestamp, ok := strings.CutPrefix(strings.TrimSuffix(name, ".gz"), base+"-")
+	if !ok {
+		return false
+	}
+	timestamp, ok = strings.CutSuffix(timestamp, extension)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(rotatedTimeFormat, timestamp)
+	return err == nil
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(target)
+	if _, err := io.Copy(writer, source); err != nil {
+		_ = target.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		_ = target.Close()
+		return err
+	}
+	if err := target.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// closeFile syncs and closes the current file, so that rotated files are complete on disk. Must be called with the
+// lock held.
+func (d *FileDispatcher) closeFile() error {
+	if d.file == nil {
+		return nil
+	}
+
+	syncErr := d.file.Sync()
+	closeErr := d.file.Close()
+	d.file = nil
+
+	if syncErr != nil {
+		return syncErr
+	}
+	return closeErr
+}
+
+// Close closes the file and waits for the compression of rotated files. The file is reopened by the next message.
+func (d *FileDispatcher) Close() error {
+	d.mu.Lock()
+	err := d.closeFile()
+	d.mu.Unlock()
+
+	d.cleanupWg.Wait()
+
+	return err
+}
+
+func (d *FileDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"path":           "required",
+		"template":       "omitempty",
+		"maxBytes":       "omitempty,min=1",
+		"rotateInterval": "omitempty",
+		"maxBackups":     "omitempty,min=1",
+		"compress":       "omitempty,boolean",
+		"sync":           "omitempty,oneof=always never",
+	}
+}
+
+func (d *FileDispatcher) ValidateConfig(config map[string]interface{}) error {
+	if text, ok := config["template"].(string); ok && text != "" {
+		if _, err := template.New("line").Parse(text); err != nil {
+			return err
+		}
+	}
+
+	if interval, ok := config["rotateInterval"].(string); ok && interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil {
+			return err
+		}
+		if parsed <= 0 {
+			return fmt.Errorf("rotation interval must be positive")
+		}
+	}
+
+	return nil
+}
+
+func (d *FileDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = fileConfig{
+		path: filepath.Clean(config["path"].(string)),
+		sync: fileSyncNever,
+	}
+
+	if text, ok := config["template"].(string); ok && text != "" {
+		d.config.template = template.Must(template.New("line").Parse(text))
+	}
+	if maxBytes, ok := config["maxBytes"].(float64); ok {
+		d.config.maxBytes = int64(math.Round(maxBytes))
+	}
+	if interval, ok := config["rotateInterval"].(string); ok && interval != "" {
+		d.config.rotateInterval, _ = time.ParseDuration(interval)
+	}
+	if maxBackups, ok := config["maxBackups"].(float64); ok {
+		d.config.maxBackups = int(math.Round(maxBackups))
+	}
+	if compress, ok := config["compress"].(bool); ok {
+		d.config.compress = compress
+	}
+	if sync, ok := config["sync"].(string); ok && sync != "" {
+		d.config.sync = sync
+	}
+}
+
+func NewFileDispatcher() *FileDispatcher {
+	return &FileDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+	}
+}
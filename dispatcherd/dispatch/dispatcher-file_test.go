@@ -0,0 +1,182 @@
+package dispatch_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	if strings.HasSuffix(path, ".gz") {
+		reader, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		scanner = bufio.NewScanner(reader)
+	}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+
+	return lines
+}
+
+func TestFileDispatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive", "messages.jsonl")
+
+	dispatcher := dispatch.NewFileDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{"path": path, "sync": "always"})
+
+	msg := dispatch.NewMessage("Disk full", "/var is at 98%", map[string]string{"host": "db1"})
+	msg.Key = "disk-db1"
+	msg.Status = dispatch.StatusFiring
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+	require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("second", "", nil)))
+	require.NoError(t, dispatcher.Close())
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.NotEmpty(t, entry["time"])
+	delete(entry, "time")
+	assert.Equal(t, map[string]interface{}{
+		"id":      msg.ID,
+		"title":   "Disk full",
+		"message": "/var is at 98%",
+		"tags":    map[string]interface{}{"host": "db1"},
+		"key":     "disk-db1",
+		"status":  "firing",
+	}, entry)
+
+	assert.Contains(t, lines[1], `"title":"second"`)
+	assert.NotContains(t, lines[1], `"tags"`)
+}
+
+func TestFileDispatcherTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	dispatcher := dispatch.NewFileDispatcher()
+	config := map[string]interface{}{
+		"path":     path,
+		"template": `{{ .Time.Format "2006-01-02" }} [{{ index .Tags "severity" }}] {{ .Title }}`,
+	}
+	require.NoError(t, dispatcher.ValidateConfig(config))
+	dispatcher.SetConfig(config)
+
+	msg := dispatch.NewMessage("Disk full", "", map[string]string{"severity": "warning"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+	require.NoError(t, dispatcher.Close())
+
+	assert.Equal(t, []string{time.Now().Format("2006-01-02") + " [warning] Disk full"}, readLines(t, path))
+}
+
+func TestFileDispatcherInvalidConfig(t *testing.T) {
+	dispatcher := dispatch.NewFileDispatcher()
+
+	assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"path": "x", "template": "{{ .Title"}))
+	assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"path": "x", "rotateInterval": "daily"}))
+	assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"path": "x", "rotateInterval": "-1h"}))
+}
+
+func TestFileDispatcherRotation(t *testing.T) {
+	t.Run("by size with retention and compression", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "messages.jsonl")
+
+		dispatcher := dispatch.NewFileDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{
+			"path":       path,
+			"maxBytes":   float64(150),
+			"maxBackups": float64(2),
+			"compress":   true,
+		})
+
+		// every line exceeds half of the maximum size, so each message starts a new file
+		for i := range 5 {
+			msg := dispatch.NewMessage("message "+string(rune('a'+i)), strings.Repeat("x", 20), nil)
+			require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+		}
+		require.NoError(t, dispatcher.Close())
+
+		current := readLines(t, path)
+		require.Len(t, current, 1)
+		assert.Contains(t, current[0], "message e")
+
+		rotated, err := filepath.Glob(filepath.Join(dir, "messages-*.jsonl*"))
+		require.NoError(t, err)
+		require.Len(t, rotated, 2)
+		for i, name := range rotated {
+			assert.True(t, strings.HasSuffix(name, ".jsonl.gz"), name)
+			lines := readLines(t, name)
+			require.Len(t, lines, 1)
+			assert.Contains(t, lines[0], "message "+string(rune('c'+i)))
+		}
+	})
+
+	t.Run("retention keeps unrelated files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "alerts.log")
+		unrelated := filepath.Join(dir, "alerts-1h.log")
+		require.NoError(t, os.WriteFile(unrelated, []byte("{}\n"), 0640))
+
+		dispatcher := dispatch.NewFileDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{
+			"path":       path,
+			"maxBytes":   float64(150),
+			"maxBackups": float64(1),
+		})
+
+		for range 3 {
+			msg := dispatch.NewMessage("message", strings.Repeat("x", 20), nil)
+			require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+		}
+		require.NoError(t, dispatcher.Close())
+
+		assert.FileExists(t, unrelated)
+		rotated, err := filepath.Glob(filepath.Join(dir, "alerts-*.log"))
+		require.NoError(t, err)
+		assert.Len(t, rotated, 2)
+	})
+
+	t.Run("by time", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "messages.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0640))
+		yesterday := time.Now().Add(-25 * time.Hour)
+		require.NoError(t, os.Chtimes(path, yesterday, yesterday))
+
+		dispatcher := dispatch.NewFileDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{"path": path, "rotateInterval": "24h"})
+
+		require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("today", "", nil)))
+		require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("today", "", nil)))
+		require.NoError(t, dispatcher.Close())
+
+		assert.Len(t, readLines(t, path), 2)
+		rotated, err := filepath.Glob(filepath.Join(dir, "messages-*.jsonl"))
+		require.NoError(t, err)
+		require.Len(t, rotated, 1)
+		assert.Equal(t, []string{"{}"}, readLines(t, rotated[0]))
+	})
+}
@@ -0,0 +1,109 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gotify priorities for the priority levels 1 to 5
+var gotifyPriorities = [...]int{1: 1, 2: 3, 3: 5, 4: 8, 5: 10}
+
+type gotifyConfig struct {
+	serverURL   string
+	appToken    string
+	priority    *int
+	priorityTag string
+	extras      map[string]interface{}
+}
+
+// GotifyDispatcher sends messages to a Gotify server as an application.
+type GotifyDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config gotifyConfig
+}
+
+type gotifyPayload struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority *int                   `json:"priority,omitempty"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (d *GotifyDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	payload := gotifyPayload{
+		Title:    msg.Title,
+		Message:  msg.Message,
+		Priority: d.priority(msg),
+		Extras:   d.config.extras,
+	}
+	header := http.Header{"X-Gotify-Key": {d.config.appToken}}
+
+	if _, err := postJSON(ctx, d.client, d.config.serverURL+"/message", payload, header); err != nil {
+		return err
+	}
+
+	d.logger.DebugContext(ctx, "sent message to gotify")
+
+	return nil
+}
+
+// priority takes the priority from the priority tag of the message, as a number from 0 to 10 or a priority name.
+// Messages without a valid priority tag use the configured priority, or the default priority of the application.
+func (d *GotifyDispatcher) priority(msg *Message) *int {
+	value, ok := msg.Tags[d.config.priorityTag]
+	if !ok {
+		return d.config.priority
+	}
+
+	if priority, err := strconv.Atoi(value); err == nil && priority >= 0 && priority <= 10 {
+		return &priority
+	}
+	if level, ok := priorityLevel(value); ok {
+		priority := gotifyPriorities[level]
+		return &priority
+	}
+
+	return d.config.priority
+}
+
+func (d *GotifyDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"serverUrl":   "required,http_url",
+		"appToken":    "required",
+		"priority":    "omitempty,min=0,max=10",
+		"priorityTag": "omitempty",
+		"extras":      "omitempty",
+	}
+}
+
+func (d *GotifyDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = gotifyConfig{
+		serverURL:   strings.TrimSuffix(config["serverUrl"].(string), "/"),
+		appToken:    config["appToken"].(string),
+		priorityTag: defaultPriorityTag,
+	}
+
+	if priority, ok := config["priority"].(float64); ok {
+		rounded := int(math.Round(priority))
+		d.config.priority = &rounded
+	}
+	if priorityTag, ok := config["priorityTag"].(string); ok && priorityTag != "" {
+		d.config.priorityTag = priorityTag
+	}
+	if extras, ok := config["extras"].(map[string]interface{}); ok {
+		d.config.extras = extras
+	}
+}
+
+func NewGotifyDispatcher() *GotifyDispatcher {
+	return &GotifyDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
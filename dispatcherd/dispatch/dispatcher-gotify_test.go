@@ -0,0 +1,69 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGotifyDispatcher(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "app-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	extras := map[string]interface{}{
+		"client::display": map[string]interface{}{"contentType": "text/markdown"},
+	}
+
+	t.Run("without configured priority", func(t *testing.T) {
+		dispatcher := dispatch.NewGotifyDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{"serverUrl": server.URL + "/", "appToken": "app-token"})
+
+		require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil)))
+		assert.Equal(t, map[string]interface{}{"title": "title", "message": "message"}, received)
+	})
+
+	dispatcher := dispatch.NewGotifyDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"serverUrl":   server.URL,
+		"appToken":    "app-token",
+		"priority":    float64(4),
+		"priorityTag": "severity",
+		"extras":      extras,
+	})
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		priority float64
+	}{
+		{"configured priority", nil, 4},
+		{"numeric priority tag", map[string]string{"severity": "0"}, 0},
+		{"named priority tag", map[string]string{"severity": "critical"}, 10},
+		{"unknown priority tag", map[string]string{"severity": "unknown"}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", tt.tags)))
+
+			assert.Equal(t, "title", received["title"])
+			assert.Equal(t, "message", received["message"])
+			assert.Equal(t, tt.priority, received["priority"])
+			assert.Equal(t, extras, received["extras"])
+		})
+	}
+}
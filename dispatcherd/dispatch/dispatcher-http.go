@@ -0,0 +1,85 @@
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// HTTPStatusError is returned by the HTTP based dispatchers if the receiver rejected the request.
+type HTTPStatusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status %d: %s", e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+func newWebhookClient() *http.Client {
+	return &http.Client{Timeout: webhookTimeout}
+}
+
+// postJSON sends the payload as JSON and returns the response body, or an HTTPStatusError for a non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}, header http.Header) ([]byte, error) {
+	return sendJSON(ctx, client, http.MethodPost, url, payload, header)
+}
+
+func sendJSON(ctx context.Context, client *http.Client, method, url string, payload interface{}, header http.Header) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return send(ctx, client, method, url, "application/json", body, header)
+}
+
+func send(ctx context.Context, client *http.Client, method, url, contentType string, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// the body is only used for error details and small acknowledgements, limit what is read
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}
+	}
+
+	return respBody, nil
+}
+
+// sortedTagNames returns the tag names in a stable order for rendering.
+func sortedTagNames(tags map[string]string) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
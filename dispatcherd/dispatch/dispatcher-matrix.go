@@ -0,0 +1,155 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	matrixMaxAttempts  = 3
+	matrixRetryBackoff = time.Second
+)
+
+type matrixConfig struct {
+	homeserverURL string
+	accessToken   string
+	roomID        string
+	msgType       string
+}
+
+// MatrixDispatcher sends messages as m.room.message events to a Matrix room with the client-server API.
+type MatrixDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config matrixConfig
+}
+
+type matrixMessageEvent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func (d *MatrixDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	// the homeserver ignores a transaction it has already seen, which makes the retries below idempotent. Every send
+	// gets its own transaction, as the same message may be sent again, e.g. by several rules or transformed.
+	txnID := msg.ID + "-" + uuid.NewString()
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		d.config.homeserverURL, url.PathEscape(d.config.roomID), url.PathEscape(txnID))
+	header := http.Header{"Authorization": {"Bearer " + d.config.accessToken}}
+	event := d.event(msg)
+
+	for attempt := 1; ; attempt++ {
+		_, err := sendJSON(ctx, d.client, http.MethodPut, endpoint, event, header)
+		if err == nil {
+			d.logger.DebugContext(ctx, "sent matrix message to room "+d.config.roomID)
+			return nil
+		}
+
+		retryAfter, retryable := matrixRetryAfter(err)
+		if !retryable || attempt == matrixMaxAttempts {
+			return fmt.Errorf("sending matrix message to room %s: %w", d.config.roomID, err)
+		}
+
+		d.logger.WarnContext(ctx, fmt.Sprintf("failed to send matrix message, retrying in %s", retryAfter),
+			logging.FieldError, err)
+
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *MatrixDispatcher) event(msg *Message) matrixMessageEvent {
+	plain := []string{msg.Title}
+	formatted := []string{"<strong>" + html.EscapeString(msg.Title) + "</strong>"}
+
+	if msg.Message != "" {
+		plain = append(plain, msg.Message)
+		formatted = append(formatted, strings.ReplaceAll(html.EscapeString(msg.Message), "\n", "<br>"))
+	}
+
+	if len(msg.Tags) > 0 {
+		var tags, formattedTags []string
+		for _, name := range sortedTagNames(msg.Tags) {
+			tag := name + "=" + msg.Tags[name]
+			tags = append(tags, tag)
+			formattedTags = append(formattedTags, "<code>"+html.EscapeString(tag)+"</code>")
+		}
+		plain = append(plain, strings.Join(tags, " "))
+		formatted = append(formatted, strings.Join(formattedTags, " "))
+	}
+
+	return matrixMessageEvent{
+		MsgType:       d.config.msgType,
+		Body:          strings.Join(plain, "\n\n"),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: strings.Join(formatted, "<br><br>"),
+	}
+}
+
+// matrixRetryAfter decides whether a failed request is retried, and when. Rate limited requests are retried after
+// the delay requested by the homeserver, server and transport errors after a fixed backoff.
+func matrixRetryAfter(err error) (time.Duration, bool) {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return matrixRetryBackoff, !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	if statusErr.StatusCode == http.StatusTooManyRequests {
+		var body struct {
+			RetryAfterMs int64 `json:"retry_after_ms"`
+		}
+		if json.Unmarshal(statusErr.Body, &body) == nil && body.RetryAfterMs > 0 {
+			return time.Duration(body.RetryAfterMs) * time.Millisecond, true
+		}
+		return matrixRetryBackoff, true
+	}
+
+	return matrixRetryBackoff, statusErr.StatusCode >= http.StatusInternalServerError
+}
+
+func (d *MatrixDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"homeserverUrl": "required,http_url",
+		"accessToken":   "required",
+		"roomId":        "required,startswith=!",
+		"msgType":       "omitempty,oneof=m.text m.notice",
+	}
+}
+
+func (d *MatrixDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = matrixConfig{
+		homeserverURL: strings.TrimSuffix(config["homeserverUrl"].(string), "/"),
+		accessToken:   config["accessToken"].(string),
+		roomID:        config["roomId"].(string),
+		msgType:       "m.text",
+	}
+
+	if msgType, ok := config["msgType"].(string); ok && msgType != "" {
+		d.config.msgType = msgType
+	}
+}
+
+func NewMatrixDispatcher() *MatrixDispatcher {
+	return &MatrixDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
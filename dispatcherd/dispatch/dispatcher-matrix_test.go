@@ -0,0 +1,120 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type matrixRecorder struct {
+	mu        sync.Mutex
+	responses []int
+	paths     []string
+	events    []map[string]string
+}
+
+func (rec *matrixRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rec.paths = append(rec.paths, r.URL.EscapedPath())
+	rec.events = append(rec.events, event)
+
+	status := http.StatusOK
+	if len(rec.responses) > 0 {
+		status, rec.responses = rec.responses[0], rec.responses[1:]
+	}
+	w.WriteHeader(status)
+	switch status {
+	case http.StatusTooManyRequests:
+		_, _ = w.Write([]byte(`{"errcode": "M_LIMIT_EXCEEDED", "retry_after_ms": 10}`))
+	case http.StatusForbidden:
+		_, _ = w.Write([]byte(`{"errcode": "M_FORBIDDEN", "error": "not in room"}`))
+	default:
+		_, _ = w.Write([]byte(`{"event_id": "$event"}`))
+	}
+}
+
+func setupMatrixDispatcher(t *testing.T, responses ...int) (*dispatch.MatrixDispatcher, *matrixRecorder) {
+	t.Helper()
+
+	recorder := &matrixRecorder{responses: responses}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	dispatcher := dispatch.NewMatrixDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"homeserverUrl": server.URL,
+		"accessToken":   "token",
+		"roomId":        "!ops:example.com",
+		"msgType":       "m.notice",
+	})
+
+	return dispatcher, recorder
+}
+
+func TestMatrixDispatcher(t *testing.T) {
+	dispatcher, recorder := setupMatrixDispatcher(t)
+
+	msg := dispatch.NewMessage("Backup <failed>", "exit code 2\nsee logs", map[string]string{"host": "db1"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	require.Len(t, recorder.events, 1)
+	assert.True(t, strings.HasPrefix(recorder.paths[0],
+		"/_matrix/client/v3/rooms/%21ops:example.com/send/m.room.message/"+msg.ID+"-"), recorder.paths[0])
+	assert.Equal(t, map[string]string{
+		"msgtype":        "m.notice",
+		"body":           "Backup <failed>\n\nexit code 2\nsee logs\n\nhost=db1",
+		"format":         "org.matrix.custom.html",
+		"formatted_body": "<strong>Backup &lt;failed&gt;</strong><br><br>exit code 2<br>see logs<br><br><code>host=db1</code>",
+	}, recorder.events[0])
+}
+
+func TestMatrixDispatcherRetries(t *testing.T) {
+	t.Run("retries with the same transaction", func(t *testing.T) {
+		dispatcher, recorder := setupMatrixDispatcher(t, http.StatusTooManyRequests)
+
+		msg := dispatch.NewMessage("title", "message", nil)
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		require.Len(t, recorder.paths, 2)
+		assert.Equal(t, recorder.paths[0], recorder.paths[1])
+	})
+
+	t.Run("sends the same message again in a new transaction", func(t *testing.T) {
+		dispatcher, recorder := setupMatrixDispatcher(t)
+
+		msg := dispatch.NewMessage("title", "message", nil)
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		require.Len(t, recorder.paths, 2)
+		assert.NotEqual(t, recorder.paths[0], recorder.paths[1])
+	})
+
+	t.Run("does not retry rejected messages", func(t *testing.T) {
+		dispatcher, recorder := setupMatrixDispatcher(t, http.StatusForbidden)
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+		assert.ErrorContains(t, err, "not in room")
+		assert.Len(t, recorder.paths, 1)
+	})
+}
@@ -0,0 +1,253 @@
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"dispatcherd/logging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+const defaultMQTTTimeout = 10 * time.Second
+
+var ErrInvalidTopic = errors.New("invalid topic")
+
+var mqttSchemes = []string{"tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss"}
+
+var mqttSecureSchemes = []string{"ssl", "tls", "mqtts", "wss"}
+
+type mqttConfig struct {
+	brokerURL string
+	topic     *template.Template
+	qos       byte
+	retain    bool
+	clientID  string
+	username  string
+	password  string
+	timeout   time.Duration
+	secure    bool
+	tls       tlsClientConfig
+}
+
+// MQTTDispatcher publishes messages as JSON to an MQTT broker. The connection is opened with the first message, kept
+// open and reestablished automatically if it is lost.
+type MQTTDispatcher struct {
+	logger *slog.Logger
+	config mqttConfig
+
+	mu     sync.Mutex
+	client paho.Client
+}
+
+func (d *MQTTDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	record := newMessageRecord(msg, time.Now())
+
+	var topic bytes.Buffer
+	if err := d.config.topic.Execute(&topic, record); err != nil {
+		return err
+	}
+	if err := validateTopic(topic.String()); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	client, err := d.connect()
+	if err != nil {
+		return err
+	}
+
+	token := client.Publish(topic.String(), d.config.qos, d.config.retain, payload)
+	if err := d.wait(ctx, token); err != nil {
+		return fmt.Errorf("publishing to topic '%s': %w", topic.String(), err)
+	}
+
+	d.logger.DebugContext(ctx, "published message to mqtt topic "+topic.String())
+
+	return nil
+}
+
+// connect returns the connected client, connecting first if there is none yet.
+func (d *MQTTDispatcher) connect() (paho.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(d.config.brokerURL).
+		SetClientID(d.config.clientID).
+		SetUsername(d.config.username).
+		SetPassword(d.config.password).
+		SetConnectTimeout(d.config.timeout).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			d.logger.Warn("lost connection to mqtt broker "+d.config.brokerURL, logging.FieldError, err)
+		}).
+		SetReconnectingHandler(func(paho.Client, *paho.ClientOptions) {
+			d.logger.Info("reconnecting to mqtt broker " + d.config.brokerURL)
+		})
+
+	if d.config.secure {
+		tlsConfig, err := d.config.tls.load()
+		if err != nil {
+			return nil, err
+		}
+		options.SetTLSConfig(tlsConfig)
+	}
+
+	client := paho.NewClient(options)
+	token := client.Connect()
+	if !token.WaitTimeout(d.config.timeout) {
+		client.Disconnect(0)
+		return nil, fmt.Errorf("connecting to mqtt broker %s: timed out", d.config.brokerURL)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("connecting to mqtt broker %s: %w", d.config.brokerURL, err)
+	}
+
+	d.client = client
+
+	return client, nil
+}
+
+func (d *MQTTDispatcher) wait(ctx context.Context, token paho.Token) error {
+	timer := time.NewTimer(d.config.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-timer.C:
+		return fmt.Errorf("timed out after %s", d.config.timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close disconnects from the broker, the next message connects again.
+func (d *MQTTDispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.client != nil {
+		// give in-flight messages a moment to complete
+		d.client.Disconnect(250)
+		d.client = nil
+	}
+
+	return nil
+}
+
+// validateTopic rejects topics which cannot be published to, e.g. because a tag used in the topic template is
+// missing or contains wildcards.
+func validateTopic(topic string) error {
+	if topic == "" || strings.ContainsAny(topic, "+#\x00") {
+		return fmt.Errorf("%w: '%s'", ErrInvalidTopic, topic)
+	}
+	return nil
+}
+
+func parseTopicTemplate(text string) (*template.Template, error) {
+	return template.New("topic").Option("missingkey=error").Parse(text)
+}
+
+func (d *MQTTDispatcher) ConfigSchema() map[string]interface{} {
+	schema := map[string]interface{}{
+		"brokerUrl": "required,url",
+		"topic":     "required",
+		"qos":       "omitempty,min=0,max=2",
+		"retain":    "omitempty,boolean",
+		"clientId":  "omitempty,max=23",
+		"username":  "omitempty",
+		"password":  "omitempty",
+		"timeout":   "omitempty",
+	}
+	maps.Copy(schema, tlsClientConfigSchema)
+
+	return schema
+}
+
+func (d *MQTTDispatcher) ValidateConfig(config map[string]interface{}) error {
+	brokerURL, err := url.Parse(fmt.Sprint(config["brokerUrl"]))
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(mqttSchemes, brokerURL.Scheme) {
+		return fmt.Errorf("unsupported broker url scheme '%s'", brokerURL.Scheme)
+	}
+
+	if _, err := parseTopicTemplate(fmt.Sprint(config["topic"])); err != nil {
+		return err
+	}
+
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return err
+		}
+		if parsed <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+	}
+
+	return nil
+}
+
+func (d *MQTTDispatcher) SetConfig(config map[string]interface{}) {
+	topic, _ := parseTopicTemplate(config["topic"].(string))
+	brokerURL, _ := url.Parse(config["brokerUrl"].(string))
+
+	d.config = mqttConfig{
+		brokerURL: config["brokerUrl"].(string),
+		topic:     topic,
+		clientID:  "dispatcherd-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8],
+		timeout:   defaultMQTTTimeout,
+		secure:    brokerURL != nil && slices.Contains(mqttSecureSchemes, brokerURL.Scheme),
+		tls:       parseTLSClientConfig(config),
+	}
+
+	if qos, ok := config["qos"].(float64); ok {
+		d.config.qos = byte(math.Round(qos))
+	}
+	if retain, ok := config["retain"].(bool); ok {
+		d.config.retain = retain
+	}
+	if clientID, ok := config["clientId"].(string); ok && clientID != "" {
+		d.config.clientID = clientID
+	}
+	if username, ok := config["username"].(string); ok {
+		d.config.username = username
+	}
+	if password, ok := config["password"].(string); ok {
+		d.config.password = password
+	}
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		d.config.timeout, _ = time.ParseDuration(timeout)
+	}
+}
+
+func NewMQTTDispatcher() *MQTTDispatcher {
+	return &MQTTDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+	}
+}
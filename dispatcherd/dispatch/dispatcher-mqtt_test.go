@@ -0,0 +1,160 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type publishedMessage struct {
+	topic   string
+	payload []byte
+}
+
+// startBroker starts an embedded broker, which accepts the user "dispatcherd" and passes published messages on. The
+// returned function stops the broker before the end of the test.
+func startBroker(t *testing.T, address string) (*mqtt.Server, string, <-chan publishedMessage, func()) {
+	t.Helper()
+
+	server := mqtt.New(&mqtt.Options{InlineClient: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	require.NoError(t, server.AddHook(new(auth.Hook), &auth.Options{
+		Ledger: &auth.Ledger{
+			Users: auth.Users{"dispatcherd": {Password: "secret", ACL: auth.Filters{"alerts/#": auth.ReadWrite}}},
+		},
+	}))
+
+	listener := listeners.NewTCP(listeners.Config{ID: "tcp", Address: address})
+	require.NoError(t, server.AddListener(listener))
+	require.NoError(t, server.Serve())
+	stop := sync.OnceFunc(func() {
+		_ = server.Close()
+	})
+	t.Cleanup(stop)
+
+	published := make(chan publishedMessage, 10)
+	require.NoError(t, server.Subscribe("alerts/#", 1, func(_ *mqtt.Client, _ packets.Subscription, pk packets.Packet) {
+		published <- publishedMessage{topic: pk.TopicName, payload: pk.Payload}
+	}))
+
+	return server, listener.Address(), published, stop
+}
+
+func receivePublished(t *testing.T, published <-chan publishedMessage) publishedMessage {
+	t.Helper()
+
+	select {
+	case msg := <-published:
+		return msg
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "no mqtt message received")
+		return publishedMessage{}
+	}
+}
+
+func setupMQTTDispatcher(t *testing.T, config map[string]interface{}) *dispatch.MQTTDispatcher {
+	t.Helper()
+
+	dispatcher := dispatch.NewMQTTDispatcher()
+	require.NoError(t, dispatcher.ValidateConfig(config))
+	dispatcher.SetConfig(config)
+	t.Cleanup(func() {
+		_ = dispatcher.Close()
+	})
+
+	return dispatcher
+}
+
+func TestMQTTDispatcher(t *testing.T) {
+	server, address, published, _ := startBroker(t, "127.0.0.1:0")
+
+	dispatcher := setupMQTTDispatcher(t, map[string]interface{}{
+		"brokerUrl": "tcp://" + address,
+		"topic":     "alerts/{{ .Tags.host }}",
+		"qos":       float64(1),
+		"retain":    true,
+		"username":  "dispatcherd",
+		"password":  "secret",
+	})
+
+	msg := dispatch.NewMessage("Disk full", "/var is at 98%", map[string]string{"host": "db1"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	received := receivePublished(t, published)
+	assert.Equal(t, "alerts/db1", received.topic)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(received.payload, &record))
+	assert.Equal(t, msg.ID, record["id"])
+	assert.Equal(t, "Disk full", record["title"])
+	assert.Equal(t, map[string]interface{}{"host": "db1"}, record["tags"])
+
+	retained, ok := server.Topics.Retained.Get("alerts/db1")
+	require.True(t, ok)
+	assert.Equal(t, received.payload, retained.Payload)
+
+	t.Run("rejects invalid topics", func(t *testing.T) {
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("no host", "", nil))
+		assert.Error(t, err)
+
+		err = dispatcher.Dispatch(context.Background(), dispatch.NewMessage("wildcard", "", map[string]string{"host": "#"}))
+		assert.ErrorIs(t, err, dispatch.ErrInvalidTopic)
+	})
+}
+
+func TestMQTTDispatcherReconnect(t *testing.T) {
+	_, address, published, stop := startBroker(t, "127.0.0.1:0")
+
+	dispatcher := setupMQTTDispatcher(t, map[string]interface{}{
+		"brokerUrl": "tcp://" + address,
+		"topic":     "alerts/reconnect",
+		"qos":       float64(1),
+		"username":  "dispatcherd",
+		"password":  "secret",
+	})
+
+	require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("before", "", nil)))
+	receivePublished(t, published)
+
+	// restart the broker on the same address, the dispatcher reconnects on its own
+	stop()
+	_, _, published, _ = startBroker(t, address)
+
+	assert.Eventually(t, func() bool {
+		return dispatcher.Dispatch(context.Background(), dispatch.NewMessage("after", "", nil)) == nil
+	}, 10*time.Second, 100*time.Millisecond)
+	assert.Contains(t, string(receivePublished(t, published).payload), `"title":"after"`)
+}
+
+func TestMQTTDispatcherFailed(t *testing.T) {
+	_, address, _, _ := startBroker(t, "127.0.0.1:0")
+
+	t.Run("wrong password", func(t *testing.T) {
+		dispatcher := setupMQTTDispatcher(t, map[string]interface{}{
+			"brokerUrl": "tcp://" + address,
+			"topic":     "alerts/test",
+			"username":  "dispatcherd",
+			"password":  "wrong",
+		})
+
+		assert.Error(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "", nil)))
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		dispatcher := dispatch.NewMQTTDispatcher()
+
+		assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"brokerUrl": "http://broker", "topic": "alerts"}))
+		assert.Error(t, dispatcher.ValidateConfig(map[string]interface{}{"brokerUrl": "tcp://broker", "topic": "{{ .Tags"}))
+	})
+}
@@ -0,0 +1,137 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"encoding/base64"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultPriorityTag = "priority"
+
+type ntfyConfig struct {
+	topicURL    string
+	priority    int
+	priorityTag string
+	tags        []string
+	click       string
+	token       string
+	username    string
+	password    string
+}
+
+// NtfyDispatcher publishes messages to a topic of an ntfy server.
+type NtfyDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config ntfyConfig
+}
+
+func (d *NtfyDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	header := http.Header{}
+	// non-ASCII header values are encoded as RFC 2047 encoded words, which ntfy decodes
+	header.Set("X-Title", mime.QEncoding.Encode("utf-8", msg.Title))
+
+	if priority := d.priority(msg); priority > 0 {
+		header.Set("X-Priority", strconv.Itoa(priority))
+	}
+	if len(d.config.tags) > 0 {
+		header.Set("X-Tags", mime.QEncoding.Encode("utf-8", strings.Join(d.config.tags, ",")))
+	}
+	if d.config.click != "" {
+		header.Set("X-Click", d.config.click)
+	}
+
+	switch {
+	case d.config.token != "":
+		header.Set("Authorization", "Bearer "+d.config.token)
+	case d.config.username != "":
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.config.username + ":" + d.config.password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+
+	if _, err := send(ctx, d.client, http.MethodPost, d.config.topicURL, "text/plain; charset=utf-8",
+		[]byte(msg.Message), header); err != nil {
+		return err
+	}
+
+	d.logger.DebugContext(ctx, "published message to ntfy topic "+d.config.topicURL)
+
+	return nil
+}
+
+// priority takes the priority from the priority tag of the message, as a number from 1 to 5 or a priority name.
+// Messages without a valid priority tag use the configured priority.
+func (d *NtfyDispatcher) priority(msg *Message) int {
+	value, ok := msg.Tags[d.config.priorityTag]
+	if !ok {
+		return d.config.priority
+	}
+
+	if priority, err := strconv.Atoi(value); err == nil && priority >= 1 && priority <= 5 {
+		return priority
+	}
+	if level, ok := priorityLevel(value); ok {
+		return level
+	}
+
+	return d.config.priority
+}
+
+func (d *NtfyDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"topicUrl":    "required,http_url",
+		"priority":    "omitempty,min=1,max=5",
+		"priorityTag": "omitempty",
+		"tags":        "omitempty,dive,required",
+		"click":       "omitempty,url",
+		"token":       "omitempty",
+		"username":    "omitempty",
+		"password":    "omitempty",
+	}
+}
+
+func (d *NtfyDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = ntfyConfig{
+		topicURL:    config["topicUrl"].(string),
+		priorityTag: defaultPriorityTag,
+	}
+
+	if priority, ok := config["priority"].(float64); ok {
+		d.config.priority = int(math.Round(priority))
+	}
+	if priorityTag, ok := config["priorityTag"].(string); ok && priorityTag != "" {
+		d.config.priorityTag = priorityTag
+	}
+	if tags, ok := config["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if tag, ok := tag.(string); ok {
+				d.config.tags = append(d.config.tags, tag)
+			}
+		}
+	}
+	if click, ok := config["click"].(string); ok {
+		d.config.click = click
+	}
+	if token, ok := config["token"].(string); ok {
+		d.config.token = token
+	}
+	if username, ok := config["username"].(string); ok {
+		d.config.username = username
+	}
+	if password, ok := config["password"].(string); ok {
+		d.config.password = password
+	}
+}
+
+func NewNtfyDispatcher() *NtfyDispatcher {
+	return &NtfyDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
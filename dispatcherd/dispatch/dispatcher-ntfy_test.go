@@ -0,0 +1,84 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNtfyDispatcher(t *testing.T) {
+	var received *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received, body = r, string(data)
+		_, _ = w.Write([]byte(`{"id": "abc", "event": "message"}`))
+	}))
+	defer server.Close()
+
+	dispatcher := dispatch.NewNtfyDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"topicUrl": server.URL + "/alerts",
+		"priority": float64(2),
+		"tags":     []interface{}{"warning", "computer"},
+		"click":    "https://grafana.example.com",
+		"username": "dispatcherd",
+		"password": "secret",
+	})
+
+	tests := []struct {
+		name     string
+		tags     map[string]string
+		priority string
+	}{
+		{"configured priority", nil, "2"},
+		{"numeric priority tag", map[string]string{"priority": "5"}, "5"},
+		{"named priority tag", map[string]string{"priority": "high"}, "4"},
+		{"invalid priority tag", map[string]string{"priority": "7"}, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := dispatch.NewMessage("Backup läuft", "backup of db1 started", tt.tags)
+			require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+			assert.Equal(t, "/alerts", received.URL.Path)
+			assert.Equal(t, "backup of db1 started", body)
+			assert.Equal(t, "=?utf-8?q?Backup_l=C3=A4uft?=", received.Header.Get("X-Title"))
+			assert.Equal(t, tt.priority, received.Header.Get("X-Priority"))
+			assert.Equal(t, "warning,computer", received.Header.Get("X-Tags"))
+			assert.Equal(t, "https://grafana.example.com", received.Header.Get("X-Click"))
+
+			username, password, ok := received.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "dispatcherd", username)
+			assert.Equal(t, "secret", password)
+		})
+	}
+}
+
+func TestNtfyDispatcherToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tk_secret" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"code": 40301, "error": "forbidden"}`))
+			return
+		}
+		assert.Empty(t, r.Header.Get("X-Priority"))
+	}))
+	defer server.Close()
+
+	dispatcher := dispatch.NewNtfyDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{"topicUrl": server.URL + "/alerts", "token": "tk_secret"})
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil)))
+
+	dispatcher.SetConfig(map[string]interface{}{"topicUrl": server.URL + "/alerts", "token": "tk_other"})
+	assert.ErrorContains(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil)),
+		"forbidden")
+}
@@ -0,0 +1,165 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultOpsgenieURL       = "https://api.opsgenie.com"
+	defaultOpsgenieSource    = "dispatcherd"
+	opsgenieMessageLimit     = 130
+	opsgenieAliasLimit       = 512
+	opsgenieDescriptionLimit = 15000
+)
+
+type opsgenieConfig struct {
+	apiURL      string
+	apiKey      string
+	source      string
+	priorityTag string
+	actionTag   string
+	tags        []string
+}
+
+// OpsgenieDispatcher creates and closes Opsgenie alerts with the Alert API.
+type OpsgenieDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config opsgenieConfig
+}
+
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Source      string            `json:"source"`
+}
+
+type opsgenieAction struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+func (d *OpsgenieDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	header := http.Header{"Authorization": {"GenieKey " + d.config.apiKey}}
+	alias := truncate(incidentKey(msg), opsgenieAliasLimit)
+
+	// alerts are processed asynchronously, a successful request only means the request was accepted
+	action := incidentAction(msg, d.config.actionTag)
+	if action == incidentTrigger {
+		if _, err := postJSON(ctx, d.client, d.config.apiURL+"/v2/alerts", d.alert(msg, alias), header); err != nil {
+			return err
+		}
+
+		d.logger.DebugContext(ctx, fmt.Sprintf("created opsgenie alert with alias '%s'", alias))
+		return nil
+	}
+
+	if alias == "" {
+		return fmt.Errorf("cannot %s an opsgenie alert without the key of the message", action)
+	}
+
+	endpoint := "close"
+	if action == incidentAcknowledge {
+		endpoint = "acknowledge"
+	}
+	actionURL := fmt.Sprintf("%s/v2/alerts/%s/%s?identifierType=alias", d.config.apiURL, url.PathEscape(alias), endpoint)
+	payload := opsgenieAction{Source: d.config.source, Note: truncate(msg.Title, opsgenieMessageLimit)}
+	if _, err := postJSON(ctx, d.client, actionURL, payload, header); err != nil {
+		return err
+	}
+
+	d.logger.DebugContext(ctx, fmt.Sprintf("sent %s for opsgenie alert with alias '%s'", endpoint, alias))
+
+	return nil
+}
+
+func (d *OpsgenieDispatcher) alert(msg *Message, alias string) opsgenieAlert {
+	source := d.config.source
+	if tagSource := msg.Tags["source"]; tagSource != "" {
+		source = tagSource
+	}
+
+	return opsgenieAlert{
+		Message:     truncate(msg.Title, opsgenieMessageLimit),
+		Alias:       alias,
+		Description: truncate(msg.Message, opsgenieDescriptionLimit),
+		Tags:        d.config.tags,
+		Details:     msg.Tags,
+		Priority:    d.priority(msg),
+		Source:      source,
+	}
+}
+
+// priority takes the priority from the priority tag of the message, as one of P1 to P5 or a priority name. Alerts
+// without a valid priority get the default priority of Opsgenie.
+func (d *OpsgenieDispatcher) priority(msg *Message) string {
+	value := strings.ToUpper(msg.Tags[d.config.priorityTag])
+	switch value {
+	case "P1", "P2", "P3", "P4", "P5":
+		return value
+	}
+
+	if level, ok := priorityLevel(value); ok {
+		return fmt.Sprintf("P%d", 6-level)
+	}
+
+	return ""
+}
+
+func (d *OpsgenieDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"apiKey":      "required",
+		"source":      "omitempty",
+		"priorityTag": "omitempty",
+		"actionTag":   "omitempty",
+		"tags":        "omitempty",
+		"apiUrl":      "omitempty,http_url",
+	}
+}
+
+func (d *OpsgenieDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = opsgenieConfig{
+		apiURL:      defaultOpsgenieURL,
+		apiKey:      config["apiKey"].(string),
+		source:      defaultOpsgenieSource,
+		priorityTag: defaultPriorityTag,
+		actionTag:   defaultActionTag,
+	}
+
+	if apiURL, ok := config["apiUrl"].(string); ok && apiURL != "" {
+		d.config.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+	if source, ok := config["source"].(string); ok && source != "" {
+		d.config.source = source
+	}
+	if priorityTag, ok := config["priorityTag"].(string); ok && priorityTag != "" {
+		d.config.priorityTag = priorityTag
+	}
+	if actionTag, ok := config["actionTag"].(string); ok && actionTag != "" {
+		d.config.actionTag = actionTag
+	}
+	if tags, ok := config["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if tag, ok := tag.(string); ok {
+				d.config.tags = append(d.config.tags, tag)
+			}
+		}
+	}
+}
+
+func NewOpsgenieDispatcher() *OpsgenieDispatcher {
+	return &OpsgenieDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
@@ -0,0 +1,81 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpsgenieDispatcher(t *testing.T) {
+	var request *http.Request
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "Could not authenticate", "took": 0.001}`))
+			return
+		}
+		request, received = r, nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"result": "Request will be processed", "took": 0.2, "requestId": "43a29c5c"}`))
+	}))
+	defer server.Close()
+
+	dispatcher := dispatch.NewOpsgenieDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"apiUrl": server.URL,
+		"apiKey": "api-key",
+		"tags":   []interface{}{"dispatcherd"},
+	})
+
+	t.Run("creates an alert", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "/var is at 98%", map[string]string{"priority": "critical", "host": "db1"})
+		msg.Key = "disk/db1"
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Equal(t, "/v2/alerts", request.URL.Path)
+		assert.Equal(t, map[string]interface{}{
+			"message":     "Disk full",
+			"alias":       "disk/db1",
+			"description": "/var is at 98%",
+			"tags":        []interface{}{"dispatcherd"},
+			"details":     map[string]interface{}{"priority": "critical", "host": "db1"},
+			"priority":    "P1",
+			"source":      "dispatcherd",
+		}, received)
+	})
+
+	t.Run("closes an alert", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "", nil)
+		msg.Key = "disk/db1"
+		msg.Status = dispatch.StatusResolved
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Equal(t, "/v2/alerts/disk%2Fdb1/close", request.URL.EscapedPath())
+		assert.Equal(t, "alias", request.URL.Query().Get("identifierType"))
+		assert.Equal(t, map[string]interface{}{"source": "dispatcherd", "note": "Disk full"}, received)
+	})
+
+	t.Run("acknowledges an alert", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "", map[string]string{"action": "acknowledge"})
+		msg.Key = "disk/db1"
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Equal(t, "/v2/alerts/disk%2Fdb1/acknowledge", request.URL.EscapedPath())
+	})
+
+	t.Run("rejected api key", func(t *testing.T) {
+		dispatcher := dispatch.NewOpsgenieDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{"apiUrl": server.URL, "apiKey": "other"})
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+		assert.ErrorContains(t, err, "Could not authenticate")
+	})
+}
@@ -0,0 +1,161 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultPagerDutyURL    = "https://events.pagerduty.com"
+	defaultPagerDutySource = "dispatcherd"
+	pagerDutySummaryLimit  = 1024
+)
+
+type pagerDutyConfig struct {
+	apiURL          string
+	routingKey      string
+	source          string
+	severityTag     string
+	defaultSeverity string
+	actionTag       string
+}
+
+// PagerDutyDispatcher sends messages as events to the PagerDuty Events API v2.
+type PagerDutyDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config pagerDutyConfig
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func (d *PagerDutyDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	event := pagerDutyEvent{
+		RoutingKey:  d.config.routingKey,
+		EventAction: incidentAction(msg, d.config.actionTag),
+		DedupKey:    incidentKey(msg),
+	}
+
+	if event.EventAction == incidentTrigger {
+		event.Payload = d.payload(msg)
+	} else if event.DedupKey == "" {
+		return fmt.Errorf("cannot %s a pagerduty incident without the key of the message", event.EventAction)
+	}
+
+	body, err := postJSON(ctx, d.client, d.config.apiURL+"/v2/enqueue", event, nil)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		DedupKey string `json:"dedup_key"`
+	}
+	_ = json.Unmarshal(body, &response)
+	d.logger.DebugContext(ctx, fmt.Sprintf("sent pagerduty %s event with dedup key '%s'", event.EventAction,
+		response.DedupKey))
+
+	return nil
+}
+
+func (d *PagerDutyDispatcher) payload(msg *Message) *pagerDutyPayload {
+	details := maps.Clone(msg.Tags)
+	if msg.Message != "" {
+		if details == nil {
+			details = map[string]string{}
+		}
+		if _, ok := details["message"]; !ok {
+			details["message"] = msg.Message
+		}
+	}
+
+	source := d.config.source
+	if tagSource := msg.Tags["source"]; tagSource != "" {
+		source = tagSource
+	}
+
+	return &pagerDutyPayload{
+		Summary:       truncate(msg.Title, pagerDutySummaryLimit),
+		Source:        source,
+		Severity:      d.severity(msg),
+		CustomDetails: details,
+	}
+}
+
+// severity maps the severity tag of the message to one of the severities of PagerDuty.
+func (d *PagerDutyDispatcher) severity(msg *Message) string {
+	switch strings.ToLower(msg.Tags[d.config.severityTag]) {
+	case "critical":
+		return "critical"
+	case "error", "high":
+		return "error"
+	case "warning", "warn", "medium":
+		return "warning"
+	case "info", "low", "ok":
+		return "info"
+	default:
+		return d.config.defaultSeverity
+	}
+}
+
+func (d *PagerDutyDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"routingKey":      "required",
+		"source":          "omitempty",
+		"severityTag":     "omitempty",
+		"defaultSeverity": "omitempty,oneof=critical error warning info",
+		"actionTag":       "omitempty",
+		"apiUrl":          "omitempty,http_url",
+	}
+}
+
+func (d *PagerDutyDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = pagerDutyConfig{
+		apiURL:          defaultPagerDutyURL,
+		routingKey:      config["routingKey"].(string),
+		source:          defaultPagerDutySource,
+		severityTag:     defaultSeverityTag,
+		defaultSeverity: "error",
+		actionTag:       defaultActionTag,
+	}
+
+	if apiURL, ok := config["apiUrl"].(string); ok && apiURL != "" {
+		d.config.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+	if source, ok := config["source"].(string); ok && source != "" {
+		d.config.source = source
+	}
+	if severityTag, ok := config["severityTag"].(string); ok && severityTag != "" {
+		d.config.severityTag = severityTag
+	}
+	if defaultSeverity, ok := config["defaultSeverity"].(string); ok && defaultSeverity != "" {
+		d.config.defaultSeverity = defaultSeverity
+	}
+	if actionTag, ok := config["actionTag"].(string); ok && actionTag != "" {
+		d.config.actionTag = actionTag
+	}
+}
+
+func NewPagerDutyDispatcher() *PagerDutyDispatcher {
+	return &PagerDutyDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
@@ -0,0 +1,94 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerDutyDispatcher(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/enqueue" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		received = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status": "success", "message": "Event processed", "dedup_key": "generated"}`))
+	}))
+	defer server.Close()
+
+	dispatcher := dispatch.NewPagerDutyDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"apiUrl":     server.URL + "/",
+		"routingKey": "R0UT1NG",
+		"source":     "monitoring",
+	})
+
+	t.Run("triggers an incident", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "/var is at 98%", map[string]string{"severity": "critical", "host": "db1"})
+		msg.Key = "disk-db1"
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Equal(t, map[string]interface{}{
+			"routing_key":  "R0UT1NG",
+			"event_action": "trigger",
+			"dedup_key":    "disk-db1",
+			"payload": map[string]interface{}{
+				"summary":  "Disk full",
+				"source":   "monitoring",
+				"severity": "critical",
+				"custom_details": map[string]interface{}{
+					"severity": "critical",
+					"host":     "db1",
+					"message":  "/var is at 98%",
+				},
+			},
+		}, received)
+	})
+
+	t.Run("uses the default severity", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "", map[string]string{"source": "db1"})
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.NotContains(t, received, "dedup_key")
+		payload := received["payload"].(map[string]interface{})
+		assert.Equal(t, "error", payload["severity"])
+		assert.Equal(t, "db1", payload["source"])
+	})
+
+	t.Run("resolves an incident", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "", nil)
+		msg.Key = "disk-db1"
+		msg.Status = dispatch.StatusResolved
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Equal(t, map[string]interface{}{
+			"routing_key":  "R0UT1NG",
+			"event_action": "resolve",
+			"dedup_key":    "disk-db1",
+		}, received)
+	})
+
+	t.Run("acknowledges an incident", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "", map[string]string{"action": "acknowledge"})
+		msg.DedupKey = "disk-db1"
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Equal(t, "acknowledge", received["event_action"])
+		assert.Equal(t, "disk-db1", received["dedup_key"])
+	})
+
+	t.Run("requires a key to resolve", func(t *testing.T) {
+		msg := dispatch.NewMessage("Disk full", "", map[string]string{"action": "resolve"})
+		assert.Error(t, dispatcher.Dispatch(context.Background(), msg))
+	})
+}
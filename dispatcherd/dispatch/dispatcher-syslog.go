@@ -0,0 +1,324 @@
+package dispatch
+
+import (
+	"context"
+	"crypto/tls"
+	"dispatcherd/logging"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syslogRFC5424 = "rfc5424"
+	syslogRFC3164 = "rfc3164"
+
+	defaultSyslogAppName      = "dispatcherd"
+	defaultSyslogEnterpriseID = 32473
+	defaultFacilityTag        = "facility"
+	syslogDialTimeout         = 10 * time.Second
+	syslogWriteTimeout        = 10 * time.Second
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7, "uucp": 8, "cron": 9,
+	"authpriv": 10, "ftp": 11, "ntp": 12, "security": 13, "console": 14, "solaris-cron": 15, "local0": 16,
+	"local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[string]int{
+	"emerg": 0, "emergency": 0, "alert": 1, "crit": 2, "critical": 2, "err": 3, "error": 3, "high": 3, "warning": 4,
+	"warn": 4, "medium": 4, "notice": 5, "info": 6, "informational": 6, "low": 6, "ok": 6, "debug": 7,
+}
+
+type syslogConfig struct {
+	network         string
+	address         string
+	format          string
+	facility        int
+	facilityTag     string
+	severityTag     string
+	defaultSeverity int
+	appName         string
+	hostname        string
+	enterpriseID    int
+	tls             tlsClientConfig
+}
+
+// SyslogDispatcher forwards messages to a syslog receiver. Stream connections are kept open and shared by all
+// messages, they are reopened if writing to them fails.
+type SyslogDispatcher struct {
+	logger *slog.Logger
+	config syslogConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (d *SyslogDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	frame := d.frame(msg, time.Now())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// a connection closed by the receiver is only noticed when writing to it, retry once with a new connection
+	var err error
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err = d.write(ctx, frame); err == nil {
+			d.logger.DebugContext(ctx, "sent syslog message to "+d.config.address)
+			return nil
+		}
+		d.closeConn()
+	}
+
+	return fmt.Errorf("sending syslog message to %s: %w", d.config.address, err)
+}
+
+// write sends a frame over the open connection, connecting first if necessary. Must be called with the lock held.
+func (d *SyslogDispatcher) write(ctx context.Context, frame []byte) error {
+	if d.conn == nil {
+		conn, err := d.dial(ctx)
+		if err != nil {
+			return err
+		}
+		d.conn = conn
+	}
+
+	if err := d.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		return err
+	}
+
+	_, err := d.conn.Write(frame)
+	return err
+}
+
+func (d *SyslogDispatcher) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+
+	if d.config.network != "tls" {
+		return dialer.DialContext(ctx, d.config.network, d.config.address)
+	}
+
+	tlsConfig, err := d.config.tls.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", d.config.address)
+}
+
+func (d *SyslogDispatcher) closeConn() {
+	if d.conn != nil {
+		_ = d.conn.Close()
+		d.conn = nil
+	}
+}
+
+// Close closes the connection to the receiver, it is reopened by the next message.
+func (d *SyslogDispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closeConn()
+	return nil
+}
+
+// frame formats the message as syslog message. Stream transports use octet-counting framing (RFC 6587), so that
+// messages can span multiple lines.
+func (d *SyslogDispatcher) frame(msg *Message, now time.Time) []byte {
+	var message string
+	if d.config.format == syslogRFC3164 {
+		message = d.formatRFC3164(msg, now)
+	} else {
+		message = d.formatRFC5424(msg, now)
+	}
+
+	if d.config.network == "udp" {
+		return []byte(message)
+	}
+
+	return []byte(strconv.Itoa(len(message)) + " " + message)
+}
+
+func (d *SyslogDispatcher) formatRFC5424(msg *Message, now time.Time) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s", d.priority(msg), now.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(d.config.hostname, 255), syslogHeaderField(d.config.appName, 48), os.Getpid(),
+		d.structuredData(msg), syslogText(msg))
+}
+
+func (d *SyslogDispatcher) formatRFC3164(msg *Message, now time.Time) string {
+	return fmt.Sprintf("<%d>%s %s %s[%d]: %s", d.priority(msg), now.Format(time.Stamp),
+		syslogHeaderField(d.config.hostname, 255), syslogHeaderField(d.config.appName, 32), os.Getpid(),
+		syslogText(msg))
+}
+
+// structuredData carries the message ID and its tags as the structured data elements "message" and "tags".
+func (d *SyslogDispatcher) structuredData(msg *Message) string {
+	var sd strings.Builder
+	fmt.Fprintf(&sd, `[message@%d id="%s"`, d.config.enterpriseID, escapeSDParam(msg.ID))
+	if msg.Key != "" {
+		fmt.Fprintf(&sd, ` key="%s" status="%s"`, escapeSDParam(msg.Key), escapeSDParam(string(msg.Status)))
+	}
+	sd.WriteString("]")
+
+	if len(msg.Tags) > 0 {
+		fmt.Fprintf(&sd, "[tags@%d", d.config.enterpriseID)
+		for _, name := range sortedTagNames(msg.Tags) {
+			// an empty parameter name is invalid, so such tags are left out
+			if sanitized := sdName(name); sanitized != "" {
+				fmt.Fprintf(&sd, ` %s="%s"`, sanitized, escapeSDParam(msg.Tags[name]))
+			}
+		}
+		sd.WriteString("]")
+	}
+
+	return sd.String()
+}
+
+func (d *SyslogDispatcher) priority(msg *Message) int {
+	facility := d.config.facility
+	if value, ok := msg.Tags[d.config.facilityTag]; ok {
+		if tagFacility, ok := syslogFacilities[strings.ToLower(value)]; ok {
+			facility = tagFacility
+		}
+	}
+
+	severity := d.config.defaultSeverity
+	if value, ok := msg.Tags[d.config.severityTag]; ok {
+		if tagSeverity, ok := syslogSeverity(value); ok {
+			severity = tagSeverity
+		}
+	}
+
+	return facility*8 + severity
+}
+
+// syslogSeverity parses a severity given as a number from 0 to 7 or as a name.
+func syslogSeverity(value string) (int, bool) {
+	if severity, err := strconv.Atoi(value); err == nil && severity >= 0 && severity <= 7 {
+		return severity, true
+	}
+	severity, ok := syslogSeverities[strings.ToLower(value)]
+	return severity, ok
+}
+
+func syslogText(msg *Message) string {
+	if msg.Message == "" {
+		return msg.Title
+	}
+	return msg.Title + ": " + msg.Message
+}
+
+// syslogHeaderField makes a value usable as header field, which must consist of printable ASCII characters.
+func syslogHeaderField(value string, max int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+
+	if field == "" {
+		return "-"
+	}
+	if len(field) > max {
+		return field[:max]
+	}
+	return field
+}
+
+// sdName makes a tag name usable as structured data parameter name, which excludes '=', ' ', ']' and '"'.
+func sdName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	if len(sanitized) > 32 {
+		return sanitized[:32]
+	}
+	return sanitized
+}
+
+var sdParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func escapeSDParam(value string) string {
+	return sdParamEscaper.Replace(value)
+}
+
+func (d *SyslogDispatcher) ConfigSchema() map[string]interface{} {
+	schema := map[string]interface{}{
+		"network":         "required,oneof=udp tcp tls",
+		"address":         "required,hostname_port",
+		"format":          "omitempty,oneof=rfc5424 rfc3164",
+		"facility":        "omitempty,oneof=" + strings.Join(slices.Sorted(maps.Keys(syslogFacilities)), " "),
+		"facilityTag":     "omitempty",
+		"severityTag":     "omitempty",
+		"defaultSeverity": "omitempty,oneof=" + strings.Join(slices.Sorted(maps.Keys(syslogSeverities)), " "),
+		"appName":         "omitempty",
+		"hostname":        "omitempty",
+		"enterpriseId":    "omitempty,min=1",
+	}
+	maps.Copy(schema, tlsClientConfigSchema)
+
+	return schema
+}
+
+func (d *SyslogDispatcher) SetConfig(config map[string]interface{}) {
+	hostname, _ := os.Hostname()
+
+	d.config = syslogConfig{
+		network:         config["network"].(string),
+		address:         config["address"].(string),
+		format:          syslogRFC5424,
+		facility:        syslogFacilities["user"],
+		facilityTag:     defaultFacilityTag,
+		severityTag:     defaultSeverityTag,
+		defaultSeverity: syslogSeverities["notice"],
+		appName:         defaultSyslogAppName,
+		hostname:        hostname,
+		enterpriseID:    defaultSyslogEnterpriseID,
+		tls:             parseTLSClientConfig(config),
+	}
+
+	if format, ok := config["format"].(string); ok && format != "" {
+		d.config.format = format
+	}
+	if facility, ok := syslogFacilities[fmt.Sprint(config["facility"])]; ok {
+		d.config.facility = facility
+	}
+	if facilityTag, ok := config["facilityTag"].(string); ok && facilityTag != "" {
+		d.config.facilityTag = facilityTag
+	}
+	if severityTag, ok := config["severityTag"].(string); ok && severityTag != "" {
+		d.config.severityTag = severityTag
+	}
+	if severity, ok := syslogSeverities[fmt.Sprint(config["defaultSeverity"])]; ok {
+		d.config.defaultSeverity = severity
+	}
+	if appName, ok := config["appName"].(string); ok && appName != "" {
+		d.config.appName = appName
+	}
+	if hostname, ok := config["hostname"].(string); ok && hostname != "" {
+		d.config.hostname = hostname
+	}
+	if enterpriseID, ok := config["enterpriseId"].(float64); ok {
+		d.config.enterpriseID = int(math.Round(enterpriseID))
+	}
+}
+
+func NewSyslogDispatcher() *SyslogDispatcher {
+	return &SyslogDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+	}
+}
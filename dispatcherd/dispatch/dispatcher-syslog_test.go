@@ -0,0 +1,199 @@
+package dispatch_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"dispatcherd/dispatch"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOctetCounted reads frames with octet-counting framing from the connections accepted by the listener.
+func readOctetCounted(t *testing.T, listener net.Listener) <-chan string {
+	t.Helper()
+
+	frames := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+				reader := bufio.NewReader(conn)
+				for {
+					length, err := reader.ReadString(' ')
+					if err != nil {
+						return
+					}
+					n, err := strconv.Atoi(strings.TrimSpace(length))
+					if err != nil {
+						return
+					}
+					frame := make([]byte, n)
+					if _, err := io.ReadFull(reader, frame); err != nil {
+						return
+					}
+					frames <- string(frame)
+				}
+			}()
+		}
+	}()
+
+	return frames
+}
+
+func receive(t *testing.T, frames <-chan string) string {
+	t.Helper()
+
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no syslog message received")
+		return ""
+	}
+}
+
+func TestSyslogDispatcherRFC5424(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+	frames := readOctetCounted(t, listener)
+
+	dispatcher := dispatch.NewSyslogDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"network":  "tcp",
+		"address":  listener.Addr().String(),
+		"facility": "local3",
+		"hostname": "dispatcher host",
+	})
+	defer func() {
+		_ = dispatcher.Close()
+	}()
+
+	msg := dispatch.NewMessage("Disk full", "/var is at 98%\nclean up", map[string]string{
+		"severity":  "warning",
+		"host":      "db1",
+		"team name": `ops "core"]`,
+	})
+	msg.Key = "disk-db1"
+	msg.Status = dispatch.StatusFiring
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	pattern := regexp.MustCompile(`^<156>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ dispatcher_host dispatcherd \d+ - ` +
+		regexp.QuoteMeta(`[message@32473 id="`+msg.ID+`" key="disk-db1" status="firing"]`+
+			`[tags@32473 host="db1" severity="warning" team_name="ops \"core\"\]"] Disk full: /var is at 98%`+
+			"\nclean up") + `$`)
+	assert.Regexp(t, pattern, receive(t, frames))
+
+	t.Run("facility and severity from tags", func(t *testing.T) {
+		msg := dispatch.NewMessage("Login failed", "", map[string]string{"facility": "authpriv", "severity": "2"})
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.True(t, strings.HasPrefix(receive(t, frames), "<82>1 "))
+	})
+
+	t.Run("skips tags with empty name", func(t *testing.T) {
+		msg := dispatch.NewMessage("Unnamed", "", map[string]string{"": "value", "host": "db1"})
+		require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+		assert.Contains(t, receive(t, frames), `[tags@32473 host="db1"] Unnamed`)
+	})
+
+	t.Run("reconnects after the connection was closed", func(t *testing.T) {
+		require.NoError(t, dispatcher.Close())
+
+		require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("again", "", nil)))
+		assert.Contains(t, receive(t, frames), " again")
+	})
+}
+
+func TestSyslogDispatcherRFC3164(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	dispatcher := dispatch.NewSyslogDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"network":         "udp",
+		"address":         conn.LocalAddr().String(),
+		"format":          "rfc3164",
+		"defaultSeverity": "info",
+		"appName":         "alerts",
+		"hostname":        "web1",
+	})
+	defer func() {
+		_ = dispatcher.Close()
+	}()
+
+	require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("Deployed", "version 1.2", nil)))
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^<14>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d web1 alerts\[\d+\]: Deployed: version 1\.2$`),
+		string(buf[:n]))
+}
+
+func TestSyslogDispatcherTLS(t *testing.T) {
+	// borrow the certificate of a test server, which is valid for 127.0.0.1
+	server := httptest.NewTLSServer(nil)
+	server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0600))
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: server.TLS.Certificates,
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+	frames := readOctetCounted(t, listener)
+
+	dispatcher := dispatch.NewSyslogDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{
+		"network": "tls",
+		"address": listener.Addr().String(),
+		"caFile":  caFile,
+	})
+	defer func() {
+		_ = dispatcher.Close()
+	}()
+
+	require.NoError(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("secure", "", nil)))
+	assert.Contains(t, receive(t, frames), " secure")
+
+	t.Run("rejects unknown certificates", func(t *testing.T) {
+		dispatcher := dispatch.NewSyslogDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{"network": "tls", "address": listener.Addr().String()})
+
+		assert.Error(t, dispatcher.Dispatch(context.Background(), dispatch.NewMessage("insecure", "", nil)))
+	})
+}
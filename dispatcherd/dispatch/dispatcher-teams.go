@@ -0,0 +1,147 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"log/slog"
+	"net/http"
+)
+
+const defaultSeverityTag = "severity"
+
+type teamsConfig struct {
+	webhookURL  string
+	severityTag string
+}
+
+// TeamsDispatcher posts messages as Adaptive Cards to a Microsoft Teams workflow or incoming webhook.
+type TeamsDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config teamsConfig
+}
+
+type teamsPayload struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	ContentURL  *string      `json:"contentUrl"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string                 `json:"$schema"`
+	Type    string                 `json:"type"`
+	Version string                 `json:"version"`
+	Body    []adaptiveCardElement  `json:"body"`
+	MSTeams map[string]interface{} `json:"msteams,omitempty"`
+}
+
+type adaptiveCardElement struct {
+	Type   string             `json:"type"`
+	Text   string             `json:"text,omitempty"`
+	Size   string             `json:"size,omitempty"`
+	Weight string             `json:"weight,omitempty"`
+	Color  string             `json:"color,omitempty"`
+	Wrap   bool               `json:"wrap,omitempty"`
+	Facts  []adaptiveCardFact `json:"facts,omitempty"`
+}
+
+type adaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+func (d *TeamsDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	if _, err := postJSON(ctx, d.client, d.config.webhookURL, d.payload(msg), nil); err != nil {
+		return err
+	}
+
+	d.logger.DebugContext(ctx, "posted message to teams")
+
+	return nil
+}
+
+func (d *TeamsDispatcher) payload(msg *Message) teamsPayload {
+	body := []adaptiveCardElement{
+		{
+			Type:   "TextBlock",
+			Text:   msg.Title,
+			Size:   "Large",
+			Weight: "Bolder",
+			Color:  d.themeColor(msg),
+			Wrap:   true,
+		},
+	}
+
+	if msg.Message != "" {
+		body = append(body, adaptiveCardElement{Type: "TextBlock", Text: msg.Message, Wrap: true})
+	}
+
+	if len(msg.Tags) > 0 {
+		facts := make([]adaptiveCardFact, 0, len(msg.Tags))
+		for _, name := range sortedTagNames(msg.Tags) {
+			facts = append(facts, adaptiveCardFact{Title: name, Value: msg.Tags[name]})
+		}
+		body = append(body, adaptiveCardElement{Type: "FactSet", Facts: facts})
+	}
+
+	return teamsPayload{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: adaptiveCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body:    body,
+					MSTeams: map[string]interface{}{"width": "Full"},
+				},
+			},
+		},
+	}
+}
+
+// themeColor maps the severity tag of the message to an Adaptive Card color, messages without a known severity use the
+// default color.
+func (d *TeamsDispatcher) themeColor(msg *Message) string {
+	switch severityOf(msg, d.config.severityTag) {
+	case severityCritical:
+		return "Attention"
+	case severityWarning:
+		return "Warning"
+	case severityOK:
+		return "Good"
+	default:
+		return ""
+	}
+}
+
+func (d *TeamsDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"webhookUrl":  "required,http_url",
+		"severityTag": "omitempty",
+	}
+}
+
+func (d *TeamsDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = teamsConfig{
+		webhookURL:  config["webhookUrl"].(string),
+		severityTag: defaultSeverityTag,
+	}
+
+	if severityTag, ok := config["severityTag"].(string); ok && severityTag != "" {
+		d.config.severityTag = severityTag
+	}
+}
+
+func NewTeamsDispatcher() *TeamsDispatcher {
+	return &TeamsDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
@@ -0,0 +1,70 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamsDispatcher(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	dispatcher := dispatch.NewTeamsDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{"webhookUrl": server.URL})
+
+	msg := dispatch.NewMessage("Disk full", "/var is at 98%", map[string]string{"severity": "critical", "host": "db1"})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+	expected := `{
+		"type": "message",
+		"attachments": [{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"contentUrl": null,
+			"content": {
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type": "AdaptiveCard",
+				"version": "1.4",
+				"msteams": {"width": "Full"},
+				"body": [
+					{"type": "TextBlock", "text": "Disk full", "size": "Large", "weight": "Bolder", "color": "Attention", "wrap": true},
+					{"type": "TextBlock", "text": "/var is at 98%", "wrap": true},
+					{"type": "FactSet", "facts": [{"title": "host", "value": "db1"}, {"title": "severity", "value": "critical"}]}
+				]
+			}
+		}]
+	}`
+	actual, err := json.Marshal(received)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(actual))
+}
+
+func TestTeamsDispatcherRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid card", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	dispatcher := dispatch.NewTeamsDispatcher()
+	dispatcher.SetConfig(map[string]interface{}{"webhookUrl": server.URL, "severityTag": "level"})
+
+	err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+
+	var statusErr *dispatch.HTTPStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.Contains(t, err.Error(), "invalid card")
+}
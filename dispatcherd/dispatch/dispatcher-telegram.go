@@ -0,0 +1,176 @@
+package dispatch
+
+import (
+	"context"
+	"dispatcherd/logging"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultTelegramAPIURL = "https://api.telegram.org"
+	telegramParseModeHTML = "HTML"
+	telegramParseModeMD   = "MarkdownV2"
+)
+
+// limits in characters, chosen so that a rendered message stays below the 4096 characters Telegram accepts
+const (
+	telegramTitleLimit = 256
+	telegramTagsLimit  = 512
+	telegramBodyLimit  = 3000
+)
+
+type telegramConfig struct {
+	apiURL    string
+	botToken  string
+	chatIDs   []string
+	parseMode string
+}
+
+// TelegramDispatcher sends messages to Telegram chats with the sendMessage method of the Bot API.
+type TelegramDispatcher struct {
+	logger *slog.Logger
+	client *http.Client
+	config telegramConfig
+}
+
+type telegramPayload struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+func (d *TelegramDispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", d.config.apiURL, d.config.botToken)
+	texts := d.render(msg)
+
+	for _, chatID := range d.config.chatIDs {
+		for _, text := range texts {
+			payload := telegramPayload{ChatID: chatID, Text: text, ParseMode: d.config.parseMode}
+			if _, err := postJSON(ctx, d.client, endpoint, payload, nil); err != nil {
+				return fmt.Errorf("sending telegram message to chat %s: %w", chatID, redactURL(err))
+			}
+		}
+
+		d.logger.DebugContext(ctx, "sent telegram message to chat "+chatID)
+	}
+
+	return nil
+}
+
+// render formats the message in the configured parse mode. Long messages are split into several texts, the title
+// starts the first and the tags end the last one.
+func (d *TelegramDispatcher) render(msg *Message) []string {
+	var tags []string
+	for _, name := range sortedTagNames(msg.Tags) {
+		tags = append(tags, name+"="+msg.Tags[name])
+	}
+	tagLine := truncate(strings.Join(tags, " "), telegramTagsLimit)
+
+	chunks := splitText(msg.Message, telegramBodyLimit)
+	texts := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		var parts []string
+		if i == 0 {
+			parts = append(parts, d.bold(truncate(msg.Title, telegramTitleLimit)))
+		}
+		if chunk != "" {
+			parts = append(parts, d.escape(chunk))
+		}
+		if i == len(chunks)-1 && tagLine != "" {
+			parts = append(parts, d.code(tagLine))
+		}
+		texts = append(texts, strings.Join(parts, "\n\n"))
+	}
+
+	return texts
+}
+
+func (d *TelegramDispatcher) escape(text string) string {
+	if d.config.parseMode == telegramParseModeMD {
+		return escapeMarkdownV2(text)
+	}
+	return html.EscapeString(text)
+}
+
+func (d *TelegramDispatcher) bold(text string) string {
+	if d.config.parseMode == telegramParseModeMD {
+		return "*" + escapeMarkdownV2(text) + "*"
+	}
+	return "<b>" + html.EscapeString(text) + "</b>"
+}
+
+func (d *TelegramDispatcher) code(text string) string {
+	if d.config.parseMode == telegramParseModeMD {
+		// only the backtick and the backslash have to be escaped within code entities
+		return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text) + "`"
+	}
+	return "<code>" + html.EscapeString(text) + "</code>"
+}
+
+var markdownV2Escaper = strings.NewReplacer(
+	"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "~", "\\~", "`", "\\`",
+	">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.",
+	"!", "\\!",
+)
+
+func escapeMarkdownV2(text string) string {
+	return markdownV2Escaper.Replace(text)
+}
+
+// redactURL removes the request URL from transport errors, as it contains the bot token.
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func (d *TelegramDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"botToken":  "required",
+		"chatIds":   "required,min=1",
+		"parseMode": "omitempty,oneof=MarkdownV2 HTML",
+		"apiUrl":    "omitempty,http_url",
+	}
+}
+
+func (d *TelegramDispatcher) SetConfig(config map[string]interface{}) {
+	d.config = telegramConfig{
+		apiURL:    defaultTelegramAPIURL,
+		botToken:  config["botToken"].(string),
+		parseMode: telegramParseModeHTML,
+	}
+
+	if apiURL, ok := config["apiUrl"].(string); ok && apiURL != "" {
+		d.config.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+	if parseMode, ok := config["parseMode"].(string); ok && parseMode != "" {
+		d.config.parseMode = parseMode
+	}
+
+	// chat IDs are numbers for users and groups, but "@name" strings for public channels
+	chatIDs, _ := config["chatIds"].([]interface{})
+	for _, chatID := range chatIDs {
+		switch id := chatID.(type) {
+		case string:
+			d.config.chatIDs = append(d.config.chatIDs, id)
+		case float64:
+			d.config.chatIDs = append(d.config.chatIDs, strconv.FormatFloat(id, 'f', -1, 64))
+		}
+	}
+}
+
+func NewTelegramDispatcher() *TelegramDispatcher {
+	return &TelegramDispatcher{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		client: newWebhookClient(),
+	}
+}
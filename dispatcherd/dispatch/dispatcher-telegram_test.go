@@ -0,0 +1,133 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type telegramRequest struct {
+	Path      string
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+func setupTelegramServer(t *testing.T) (*httptest.Server, func() []telegramRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []telegramRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := telegramRequest{Path: r.URL.Path}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		if request.ChatID == "-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok": true, "result": {}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []telegramRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]telegramRequest{}, requests...)
+	}
+}
+
+func TestTelegramDispatcher(t *testing.T) {
+	msg := dispatch.NewMessage("CPU <high> *now*", "load is 9.5 (limit: 4) & rising_fast", map[string]string{
+		"host": "web-1",
+	})
+
+	tests := []struct {
+		parseMode string
+		expected  string
+	}{
+		{
+			parseMode: "",
+			expected:  "<b>CPU &lt;high&gt; *now*</b>\n\nload is 9.5 (limit: 4) &amp; rising_fast\n\n<code>host=web-1</code>",
+		},
+		{
+			parseMode: "MarkdownV2",
+			expected:  "*CPU <high\\> \\*now\\**\n\nload is 9\\.5 \\(limit: 4\\) & rising\\_fast\n\n`host=web-1`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("parse mode "+tt.parseMode, func(t *testing.T) {
+			server, requests := setupTelegramServer(t)
+
+			dispatcher := dispatch.NewTelegramDispatcher()
+			dispatcher.SetConfig(map[string]interface{}{
+				"apiUrl":    server.URL + "/",
+				"botToken":  "123:secret",
+				"chatIds":   []interface{}{float64(-1001234), "@status"},
+				"parseMode": tt.parseMode,
+			})
+
+			require.NoError(t, dispatcher.Dispatch(context.Background(), msg))
+
+			received := requests()
+			require.Len(t, received, 2)
+			assert.Equal(t, "/bot123:secret/sendMessage", received[0].Path)
+			assert.Equal(t, "-1001234", received[0].ChatID)
+			assert.Equal(t, "@status", received[1].ChatID)
+			assert.Equal(t, tt.expected, received[0].Text)
+			if tt.parseMode == "" {
+				assert.Equal(t, "HTML", received[0].ParseMode)
+			} else {
+				assert.Equal(t, tt.parseMode, received[0].ParseMode)
+			}
+		})
+	}
+}
+
+func TestTelegramDispatcherFailed(t *testing.T) {
+	t.Run("rejected by the api", func(t *testing.T) {
+		server, _ := setupTelegramServer(t)
+
+		dispatcher := dispatch.NewTelegramDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{
+			"apiUrl":   server.URL,
+			"botToken": "123:secret",
+			"chatIds":  []interface{}{"-1"},
+		})
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+		assert.ErrorContains(t, err, "chat not found")
+	})
+
+	t.Run("does not leak the bot token", func(t *testing.T) {
+		server, _ := setupTelegramServer(t)
+		server.Close()
+
+		dispatcher := dispatch.NewTelegramDispatcher()
+		dispatcher.SetConfig(map[string]interface{}{
+			"apiUrl":   server.URL,
+			"botToken": "123:secret",
+			"chatIds":  []interface{}{"@status"},
+		})
+
+		err := dispatcher.Dispatch(context.Background(), dispatch.NewMessage("title", "message", nil))
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+	})
+}
@@ -2,22 +2,70 @@ package dispatch
 
 import (
 	"context"
+	"dispatcherd/ratelimit"
 	"errors"
 )
 
 var ErrUnknownDispatcherType = errors.New("unknown dispatcher type")
 
+// Dispatcher delivers messages to a receiver. A dispatcher is created once per config and SetConfig is called before
+// the first message, after which Dispatch is called concurrently by all requests. Implementations must therefore be
+// safe for concurrent use.
 type Dispatcher interface {
 	Dispatch(ctx context.Context, msg *Message) error
 	ConfigSchema() map[string]interface{}
 	SetConfig(config map[string]interface{})
 }
 
+// ConfigValidator is implemented by dispatchers whose config needs checks the schema cannot express.
+type ConfigValidator interface {
+	ValidateConfig(config map[string]interface{}) error
+}
+
 type DispatcherConfig struct {
 	Name      string                 `json:"name" validate:"required"`
 	Type      string                 `json:"type" validate:"required"`
 	IsDefault bool                   `json:"isDefault"`
 	Config    map[string]interface{} `json:"config"`
+	RateLimit *RateLimitConfig       `json:"rateLimit"`
+	Dedup     *DedupConfig           `json:"dedup"`
+	Digest    *DigestConfig          `json:"digest"`
+	Grouping  *GroupingConfig        `json:"grouping"`
+}
+
+type RateLimitConfig struct {
+	ratelimit.Limit
+	// collapse the queued messages into a single summary once the backlog exceeds this size, disabled if zero
+	SummaryThreshold int `json:"summaryThreshold"`
+	// the oldest queued message is dropped once the queue holds this many, defaults to 10000 if zero
+	MaxQueueLength int `json:"maxQueueLength"`
+}
+
+type DedupConfig struct {
+	// repeated messages are suppressed for this long after the first one was delivered
+	Window Duration `json:"window"`
+	// tags identifying repeated messages in addition to the title, ignored for messages with a dedup key
+	Tags []string `json:"tags"`
+}
+
+type DigestConfig struct {
+	// buffered messages are sent as one digest this long after the first one arrived
+	Interval Duration `json:"interval"`
+	// send the digest early once this many messages are buffered, disabled if zero
+	MaxSize int `json:"maxSize"`
+	// tag to group the messages of the digest by
+	GroupBy string `json:"groupBy"`
+}
+
+type GroupingConfig struct {
+	// tags whose values identify a group, all messages form a single group if empty
+	GroupBy []string `json:"groupBy"`
+	// how long the first message of a new group waits for siblings
+	GroupWait Duration `json:"groupWait"`
+	// how often a group is checked for new messages, sending them as one notification
+	GroupInterval Duration `json:"groupInterval"`
+	// an unchanged group is sent again after this long while its messages keep arriving, disabled if zero
+	RepeatInterval Duration `json:"repeatInterval"`
 }
 
 func DispatcherFactory(dispatcherType string) (Dispatcher, error) {
@@ -28,6 +76,30 @@ func DispatcherFactory(dispatcherType string) (Dispatcher, error) {
 		return NewCounterDispatcher(), nil
 	case "mail":
 		return NewMailDispatcher(), nil
+	case "teams":
+		return NewTeamsDispatcher(), nil
+	case "discord":
+		return NewDiscordDispatcher(), nil
+	case "telegram":
+		return NewTelegramDispatcher(), nil
+	case "matrix":
+		return NewMatrixDispatcher(), nil
+	case "ntfy":
+		return NewNtfyDispatcher(), nil
+	case "gotify":
+		return NewGotifyDispatcher(), nil
+	case "pagerduty":
+		return NewPagerDutyDispatcher(), nil
+	case "opsgenie":
+		return NewOpsgenieDispatcher(), nil
+	case "syslog":
+		return NewSyslogDispatcher(), nil
+	case "file":
+		return NewFileDispatcher(), nil
+	case "exec":
+		return NewExecDispatcher(), nil
+	case "mqtt":
+		return NewMQTTDispatcher(), nil
 	default:
 		return nil, ErrUnknownDispatcherType
 	}
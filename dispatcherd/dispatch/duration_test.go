@@ -0,0 +1,23 @@
+package dispatch_test
+
+import (
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationJSON(t *testing.T) {
+	var d dispatch.Duration
+	assert.NoError(t, json.Unmarshal([]byte(`"1h30m"`), &d))
+	assert.Equal(t, 90*time.Minute, d.Duration())
+
+	data, err := json.Marshal(d)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `"1h30m0s"`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`300`), &d))
+	assert.Error(t, json.Unmarshal([]byte(`"five minutes"`), &d))
+}
@@ -0,0 +1,26 @@
+package dispatch
+
+import "time"
+
+type EscalationPolicy struct {
+	Name  string           `json:"name" validate:"required"`
+	Steps []EscalationStep `json:"steps" validate:"required,min=1,dive"`
+}
+
+type EscalationStep struct {
+	DispatcherName string `json:"dispatcherName" validate:"required"`
+	// how long to wait for an acknowledgement before escalating to the next step
+	Timeout Duration `json:"timeout"`
+}
+
+// Escalation is the state of a message escalating through the steps of a policy, until it is acknowledged.
+type Escalation struct {
+	Policy  string           `json:"policy"`
+	Message *Message         `json:"message"`
+	Steps   []EscalationStep `json:"steps"`
+	// client which sent the message, empty without authentication
+	Owner string `json:"owner,omitempty"`
+	// index of the step notified last
+	Step   int       `json:"step"`
+	NextAt time.Time `json:"nextAt"`
+}
@@ -0,0 +1,41 @@
+package dispatch
+
+// ConditionResult is the outcome of a single rule condition.
+type ConditionResult struct {
+	RuleMatch
+	// value of the tag in the message
+	Actual     string `json:"actual,omitempty"`
+	TagMissing bool   `json:"tagMissing"`
+	Matched    bool   `json:"matched"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// RuleEvaluation is the outcome of a rule, with the results of its conditions.
+type RuleEvaluation struct {
+	RuleID           string            `json:"ruleId"`
+	DispatcherName   string            `json:"dispatcherName,omitempty"`
+	EscalationPolicy string            `json:"escalationPolicy,omitempty"`
+	Matched          bool              `json:"matched"`
+	Reason           string            `json:"reason,omitempty"`
+	Conditions       []ConditionResult `json:"conditions"`
+	Expression       *ExpressionResult `json:"expression,omitempty"`
+}
+
+// ExpressionResult is the outcome of the CEL expression of a rule.
+type ExpressionResult struct {
+	Expression string `json:"expression"`
+	Matched    bool   `json:"matched"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Evaluation explains how a message would be dispatched.
+type Evaluation struct {
+	Rules []RuleEvaluation `json:"rules"`
+	// dispatchers the message would be delivered to, including the first step of escalations
+	Dispatchers        []string `json:"dispatchers"`
+	EscalationPolicies []string `json:"escalationPolicies"`
+	// whether the default dispatchers would be used, because no rule matched
+	DefaultDispatchers bool `json:"defaultDispatchers"`
+	// silence which would mute the message
+	SilencedBy string `json:"silencedBy,omitempty"`
+}
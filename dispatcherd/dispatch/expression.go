@@ -0,0 +1,57 @@
+package dispatch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// expressionEnv declares the variables available to rule expressions.
+var expressionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("title", cel.StringType),
+		cel.Variable("message", cel.StringType),
+		cel.Variable("tags", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("now", cel.TimestampType),
+	)
+})
+
+// CompileExpression parses and type-checks a rule expression, which must evaluate to a bool.
+func CompileExpression(expression string) (cel.Program, error) {
+	env, err := expressionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to bool, not %s", ast.OutputType())
+	}
+
+	return env.Program(ast)
+}
+
+func evaluateExpression(program cel.Program, msg *Message, now time.Time) (bool, error) {
+	tags := msg.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	result, _, err := program.Eval(map[string]any{
+		"title":   msg.Title,
+		"message": msg.Message,
+		"tags":    tags,
+		"now":     now,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result == types.True, nil
+}
@@ -0,0 +1,82 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileExpression(t *testing.T) {
+	_, err := dispatch.CompileExpression(`tags.severity in ['critical', 'high'] && now.getHours() < 12`)
+	assert.NoError(t, err)
+
+	for _, expression := range []string{
+		`tags.severity ==`,
+		`title`,
+		`unknown == 'value'`,
+		`tags.severity > 5`,
+	} {
+		_, err := dispatch.CompileExpression(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestRuleExpression(t *testing.T) {
+	engine := dispatch.NewRuleEngineWithClock(func() time.Time {
+		return time.Date(2025, 11, 3, 10, 0, 0, 0, time.UTC)
+	})
+
+	rule := dispatch.Rule{
+		ID:             "severe",
+		DispatcherName: "pager",
+		Expression:     `tags.severity in ['critical', 'high'] && !title.startsWith('[test]')`,
+	}
+	require.NoError(t, rule.Compile())
+	engine.SetRules([]dispatch.Rule{
+		rule,
+		{
+			ID:             "database in the morning",
+			DispatcherName: "mail",
+			Match:          []dispatch.RuleMatch{{TagName: "service", Operator: dispatch.EQUALS, Value: "database"}},
+			Expression:     `now.getHours() < 12`,
+		},
+	})
+
+	tests := []struct {
+		name     string
+		title    string
+		tags     map[string]string
+		expected []string
+	}{
+		{"expression holds", "Disk full", map[string]string{"severity": "high"}, []string{"pager"}},
+		{"expression fails", "[test] Disk full", map[string]string{"severity": "high"}, []string{}},
+		{"missing tag", "Disk full", nil, []string{}},
+		{"conditions and expression", "Disk full", map[string]string{"service": "database"}, []string{"mail"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := engine.ProcessMessage(context.Background(), dispatch.NewMessage(tt.title, "message", tt.tags))
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, rule := range matched {
+				names = append(names, rule.DispatcherName)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+
+	t.Run("explains expression errors", func(t *testing.T) {
+		evaluations, err := engine.EvaluateRules(context.Background(), dispatch.NewMessage("Disk full", "message", nil))
+		require.NoError(t, err)
+
+		require.NotNil(t, evaluations[0].Expression)
+		assert.False(t, evaluations[0].Matched)
+		assert.Contains(t, evaluations[0].Expression.Error, "no such key")
+	})
+}
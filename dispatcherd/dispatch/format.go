@@ -0,0 +1,158 @@
+package dispatch
+
+import (
+	"strings"
+	"time"
+)
+
+// messageRecord is the JSON representation of a message handed to other programs.
+type messageRecord struct {
+	Time    time.Time         `json:"time"`
+	ID      string            `json:"id"`
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Key     string            `json:"key,omitempty"`
+	Status  MessageStatus     `json:"status,omitempty"`
+}
+
+func newMessageRecord(msg *Message, now time.Time) messageRecord {
+	return messageRecord{
+		Time:    now,
+		ID:      msg.ID,
+		Title:   msg.Title,
+		Message: msg.Message,
+		Tags:    msg.Tags,
+		Key:     msg.Key,
+		Status:  msg.Status,
+	}
+}
+
+// severity is the normalized value of the severity tag, used by dispatchers to pick colors and priorities.
+type severity int
+
+const (
+	severityUnknown severity = iota
+	severityOK
+	severityWarning
+	severityCritical
+)
+
+func severityOf(msg *Message, tagName string) severity {
+	if msg.Status == StatusResolved {
+		return severityOK
+	}
+
+	switch strings.ToLower(msg.Tags[tagName]) {
+	case "critical", "error", "high":
+		return severityCritical
+	case "warning", "warn", "medium":
+		return severityWarning
+	case "info", "low", "ok":
+		return severityOK
+	default:
+		return severityUnknown
+	}
+}
+
+// truncate shortens the text to at most max characters, marking the cut with an ellipsis.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if max <= 0 || len(runes) <= max {
+		return text
+	}
+
+	return string(runes[:max-1]) + "…"
+}
+
+// splitText splits the text into chunks of at most max characters. Chunks end at the last line break, or otherwise
+// the last space, within the limit if there is one.
+func splitText(text string, max int) []string {
+	runes := []rune(text)
+	if max <= 0 || len(runes) <= max {
+		return []string{text}
+	}
+
+	var chunks []string
+	for len(runes) > max {
+		cut := lastIndex(runes[:max], '\n')
+		if cut <= 0 {
+			cut = lastIndex(runes[:max], ' ')
+		}
+		if cut <= 0 {
+			cut = max
+		}
+
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+		// the separator the chunk was cut at is not repeated at the start of the next chunk
+		if len(runes) > 0 && (runes[0] == '\n' || runes[0] == ' ') {
+			runes = runes[1:]
+		}
+	}
+
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+
+	return chunks
+}
+
+func lastIndex(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// priorityLevel maps a priority or severity name to a level from 1, the lowest, to 5, the highest priority.
+func priorityLevel(value string) (int, bool) {
+	switch strings.ToLower(value) {
+	case "min", "lowest":
+		return 1, true
+	case "low", "info", "ok":
+		return 2, true
+	case "default", "normal", "medium", "warning", "warn":
+		return 3, true
+	case "high", "error":
+		return 4, true
+	case "max", "urgent", "critical":
+		return 5, true
+	default:
+		return 0, false
+	}
+}
+
+const defaultActionTag = "action"
+
+// actions of incident management services, selected with the action tag of a message
+const (
+	incidentTrigger     = "trigger"
+	incidentAcknowledge = "acknowledge"
+	incidentResolve     = "resolve"
+)
+
+// incidentAction resolves the incident of a resolved message. The action tag can acknowledge or resolve an incident
+// explicitly, all other messages trigger one.
+func incidentAction(msg *Message, actionTag string) string {
+	switch action := strings.ToLower(msg.Tags[actionTag]); action {
+	case incidentTrigger, incidentAcknowledge, incidentResolve:
+		return action
+	}
+
+	if msg.Status == StatusResolved {
+		return incidentResolve
+	}
+	return incidentTrigger
+}
+
+// incidentKey identifies the incident of a message at incident management services, so that repetitions are merged
+// and resolve notifications close it. Messages without an alert or dedup key have none.
+func incidentKey(msg *Message) string {
+	if msg.Key != "" {
+		return msg.Key
+	}
+	return msg.DedupKey
+}
@@ -0,0 +1,28 @@
+package dispatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		max      int
+		expected []string
+	}{
+		{"short text", "hello", 10, []string{"hello"}},
+		{"line breaks", "first line\nsecond line", 15, []string{"first line", "second line"}},
+		{"spaces", "first second third", 13, []string{"first second", "third"}},
+		{"no separator", "abcdefgh", 3, []string{"abc", "def", "gh"}},
+		{"multi-byte characters", "äöüäöü", 3, []string{"äöü", "äöü"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitText(tt.text, tt.max))
+		})
+	}
+}
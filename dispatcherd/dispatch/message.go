@@ -4,15 +4,29 @@ import (
 	"context"
 	dispatcherdContext "dispatcherd/context"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/google/uuid"
 )
 
+type MessageStatus string
+
+const (
+	StatusFiring   MessageStatus = "firing"
+	StatusResolved MessageStatus = "resolved"
+)
+
 type Message struct {
 	ID      string
 	Title   string
 	Message string
 	Tags    map[string]string
+	// identifies repeated messages, overriding the fingerprint of title and tags
+	DedupKey string
+	// identifies a stateful alert, which stays open until a message with the same key resolves it
+	Key    string
+	Status MessageStatus
 }
 
 func NewMessage(title string, message string, tags map[string]string) *Message {
@@ -24,10 +38,38 @@ func NewMessage(title string, message string, tags map[string]string) *Message {
 	}
 }
 
+// Clone returns a copy of the message with the same ID, which can be changed without affecting the original.
+func (m *Message) Clone() *Message {
+	clone := *m
+	clone.Tags = maps.Clone(m.Tags)
+
+	return &clone
+}
+
 func (m *Message) AnnotateContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, dispatcherdContext.KeyMessageID, m.ID)
 }
 
+// Fingerprint identifies repeated messages by their dedup key, or by title and the given tags if there is none.
+func (m *Message) Fingerprint(tagNames []string) string {
+	var fingerprint string
+	if m.DedupKey != "" {
+		fingerprint = "key:" + m.DedupKey
+	} else {
+		fingerprint = "title:" + m.Title
+		for _, name := range slices.Sorted(slices.Values(tagNames)) {
+			fingerprint += fmt.Sprintf(";%s=%s", name, m.Tags[name])
+		}
+	}
+
+	// a resolve notification is never a repetition of the firing message
+	if m.Status == StatusResolved {
+		fingerprint += ";resolved"
+	}
+
+	return fingerprint
+}
+
 func (m *Message) String() string {
 	tags := "None"
 	if m.Tags != nil {
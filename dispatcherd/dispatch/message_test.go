@@ -44,3 +44,22 @@ func TestAnnotateContext(t *testing.T) {
 	assert.NotEmpty(t, msgCtx.Value(dispatcherdContext.KeyMessageID))
 	assert.Equal(t, msg.ID, msgCtx.Value(dispatcherdContext.KeyMessageID))
 }
+
+func TestFingerprint(t *testing.T) {
+	msg := dispatch.NewMessage("Disk full", "Test Message", map[string]string{"host": "a", "mount": "/"})
+	same := dispatch.NewMessage("Disk full", "Other Message", map[string]string{"mount": "/", "host": "a"})
+	otherHost := dispatch.NewMessage("Disk full", "Test Message", map[string]string{"host": "b", "mount": "/"})
+
+	assert.Equal(t, msg.Fingerprint([]string{"host", "mount"}), same.Fingerprint([]string{"mount", "host"}))
+	assert.NotEqual(t, msg.Fingerprint([]string{"host"}), otherHost.Fingerprint([]string{"host"}))
+	// tags not selected are ignored
+	assert.Equal(t, msg.Fingerprint(nil), otherHost.Fingerprint(nil))
+
+	msg.DedupKey = "disk"
+	otherHost.DedupKey = "disk"
+	assert.Equal(t, msg.Fingerprint([]string{"host"}), otherHost.Fingerprint([]string{"host"}))
+
+	// resolving is not a repetition of firing
+	otherHost.Status = dispatch.StatusResolved
+	assert.NotEqual(t, msg.Fingerprint(nil), otherHost.Fingerprint(nil))
+}
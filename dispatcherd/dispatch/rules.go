@@ -4,6 +4,9 @@ import (
 	"context"
 	"dispatcherd/logging"
 	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
 )
 
 type RuleOperator string
@@ -13,28 +16,93 @@ const (
 )
 
 type RuleMatch struct {
-	TagName  string
-	Operator RuleOperator
-	Value    string
+	TagName  string       `json:"tagName" validate:"required"`
+	Operator RuleOperator `json:"operator" validate:"oneof=eq"`
+	Value    string       `json:"value"`
+}
+
+// Matches returns whether the tags satisfy the condition.
+func (m RuleMatch) Matches(tags map[string]string) bool {
+	return m.Evaluate(tags).Matched
+}
+
+// Evaluate checks the condition against the tags, explaining why it failed.
+func (m RuleMatch) Evaluate(tags map[string]string) ConditionResult {
+	result := ConditionResult{RuleMatch: m}
+
+	val, ok := tags[m.TagName]
+	if !ok {
+		result.TagMissing = true
+		result.Reason = fmt.Sprintf("tag '%s' is missing", m.TagName)
+		return result
+	}
+	result.Actual = val
+
+	switch m.Operator {
+	case EQUALS:
+		result.Matched = val == m.Value
+	default:
+		result.Reason = fmt.Sprintf("unknown operator '%s'", m.Operator)
+		return result
+	}
+
+	if !result.Matched {
+		result.Reason = fmt.Sprintf("'%s' is not %s '%s'", val, m.Operator, m.Value)
+	}
+
+	return result
 }
 
 type Rule struct {
 	ID             string
 	DispatcherName string
-	Match          []RuleMatch
+	// escalates through the dispatchers of the policy instead of notifying a single dispatcher
+	EscalationPolicy string
+	Match            []RuleMatch
+	// restricts when the rule applies, always if nil
+	Schedule *Schedule
+	// shapes the message for the dispatcher of this rule
+	Transform *Transformation
+	// CEL expression on title, message, tags and now, which must hold in addition to the conditions
+	Expression string
+
+	program cel.Program
+}
+
+// Compile compiles the expression of the rule, if it has one.
+func (r *Rule) Compile() error {
+	if r.Expression == "" {
+		return nil
+	}
+
+	program, err := CompileExpression(r.Expression)
+	if err != nil {
+		return fmt.Errorf("compiling expression of rule '%s': %w", r.ID, err)
+	}
+	r.program = program
+
+	return nil
 }
 
 type RuleEngine interface {
-	ProcessMessage(ctx context.Context, msg *Message) ([]string, error)
+	ProcessMessage(ctx context.Context, msg *Message) ([]Rule, error)
+	EvaluateRules(ctx context.Context, msg *Message) ([]RuleEvaluation, error)
 }
 
 type DefaultRuleEngine struct {
 	rules []Rule
+	now   func() time.Time
 }
 
 func NewRuleEngine() *DefaultRuleEngine {
+	return NewRuleEngineWithClock(time.Now)
+}
+
+// NewRuleEngineWithClock creates a rule engine evaluating rule schedules against the given clock.
+func NewRuleEngineWithClock(now func() time.Time) *DefaultRuleEngine {
 	return &DefaultRuleEngine{
 		rules: make([]Rule, 0),
+		now:   now,
 	}
 }
 
@@ -42,47 +110,113 @@ func (e *DefaultRuleEngine) SetRules(rules []Rule) {
 	e.rules = rules
 }
 
-func (e *DefaultRuleEngine) ProcessMessage(ctx context.Context, msg *Message) ([]string, error) {
+// ProcessMessage returns the rules matching the message.
+func (e *DefaultRuleEngine) ProcessMessage(ctx context.Context, msg *Message) ([]Rule, error) {
 	logger := logging.GetLogger(logging.MessageProcessing)
 
-	if msg.Tags == nil {
-		// no tags, so matching does not make sense
-		return []string{}, nil
-	}
-
-	var dispatchers []string
+	var matched []Rule
 	for _, rule := range e.rules {
 		logger.DebugContext(ctx, fmt.Sprintf("validating rule '%s'", rule.ID))
-		if e.ruleMatch(rule, msg.Tags) {
+		if e.ruleMatch(rule, msg) {
 			logger.DebugContext(ctx, fmt.Sprintf("matched rule '%s'", rule.ID))
-			dispatchers = append(dispatchers, rule.DispatcherName)
+			matched = append(matched, rule)
 		}
 	}
 
-	if len(dispatchers) == 0 {
+	if len(matched) == 0 {
 		// no match, return default
-		return []string{}, nil
+		return []Rule{}, nil
+	}
+
+	return matched, nil
+}
+
+// EvaluateRules explains the outcome of every rule for the message.
+func (e *DefaultRuleEngine) EvaluateRules(ctx context.Context, msg *Message) ([]RuleEvaluation, error) {
+	evaluations := make([]RuleEvaluation, 0, len(e.rules))
+	for _, rule := range e.rules {
+		evaluations = append(evaluations, e.evaluate(rule, msg))
 	}
 
-	return dispatchers, nil
+	return evaluations, nil
+}
+
+func (e *DefaultRuleEngine) ruleMatch(rule Rule, msg *Message) bool {
+	return e.evaluate(rule, msg).Matched
 }
 
-func (e *DefaultRuleEngine) ruleMatch(rule Rule, tags map[string]string) bool {
-	matched := false
+func (e *DefaultRuleEngine) evaluate(rule Rule, msg *Message) RuleEvaluation {
+	evaluation := RuleEvaluation{
+		RuleID:           rule.ID,
+		DispatcherName:   rule.DispatcherName,
+		EscalationPolicy: rule.EscalationPolicy,
+		Conditions:       make([]ConditionResult, 0, len(rule.Match)),
+	}
+
+	now := e.now()
+	if rule.Schedule != nil && !rule.Schedule.IsActive(now) {
+		evaluation.Reason = "outside of the rule schedule"
+		return evaluation
+	}
+
+	tagMissing := false
 	for _, match := range rule.Match {
-		val, ok := tags[match.TagName]
-		if !ok {
+		result := match.Evaluate(msg.Tags)
+		evaluation.Conditions = append(evaluation.Conditions, result)
+
+		if result.TagMissing {
 			// required tag does not exist
-			return false
+			tagMissing = true
+		} else if result.Matched {
+			evaluation.Matched = true
 		}
+	}
 
-		switch match.Operator {
-		case EQUALS:
-			if val == match.Value {
-				matched = true
-			}
+	switch {
+	case tagMissing:
+		evaluation.Matched = false
+		evaluation.Reason = "a required tag is missing"
+		return evaluation
+	case !evaluation.Matched && len(rule.Match) > 0:
+		evaluation.Reason = "no condition matched"
+		return evaluation
+	case rule.Expression == "":
+		if !evaluation.Matched {
+			evaluation.Reason = "no condition matched"
 		}
+		return evaluation
+	}
+
+	// rules with an expression match if it holds, in addition to any conditions
+	evaluation.Expression = e.evaluateExpression(rule, msg, now)
+	evaluation.Matched = evaluation.Expression.Matched
+	if !evaluation.Matched {
+		evaluation.Reason = "expression did not match"
+	}
+
+	return evaluation
+}
+
+func (e *DefaultRuleEngine) evaluateExpression(rule Rule, msg *Message, now time.Time) *ExpressionResult {
+	result := &ExpressionResult{Expression: rule.Expression}
+
+	program := rule.program
+	if program == nil {
+		// rules which were not loaded from files are compiled on first use
+		compiled, err := CompileExpression(rule.Expression)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		program = compiled
+	}
+
+	matched, err := evaluateExpression(program, msg, now)
+	if err != nil {
+		result.Error = err.Error()
+		return result
 	}
+	result.Matched = matched
 
-	return matched
+	return result
 }
@@ -47,7 +47,7 @@ func TestRuleEquals(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Len(t, matched, 1)
-		assert.Equal(t, "test", matched[0])
+		assert.Equal(t, "test", matched[0].DispatcherName)
 	})
 
 	t.Run("Should match with additional message tags", func(t *testing.T) {
@@ -59,7 +59,7 @@ func TestRuleEquals(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Len(t, matched, 1)
-		assert.Equal(t, "test", matched[0])
+		assert.Equal(t, "test", matched[0].DispatcherName)
 	})
 
 	t.Run("Should not match with not matching value", func(t *testing.T) {
@@ -106,7 +106,7 @@ func TestRuleMultiTag(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Len(t, matched, 1)
-		assert.Equal(t, "test", matched[0])
+		assert.Equal(t, "test", matched[0].DispatcherName)
 	})
 
 	t.Run("Should not match message with only one tag", func(t *testing.T) {
@@ -130,7 +130,7 @@ func TestRuleMultiTag(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Len(t, matched, 1)
-		assert.Equal(t, "test", matched[0])
+		assert.Equal(t, "test", matched[0].DispatcherName)
 	})
 }
 
@@ -172,8 +172,8 @@ func TestMultipleRules(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Len(t, matched, 2)
-		assert.Equal(t, "test1", matched[0])
-		assert.Equal(t, "test2", matched[1])
+		assert.Equal(t, "test1", matched[0].DispatcherName)
+		assert.Equal(t, "test2", matched[1].DispatcherName)
 	})
 
 	t.Run("Should match only one rule", func(t *testing.T) {
@@ -185,6 +185,63 @@ func TestMultipleRules(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Len(t, matched, 1)
-		assert.Equal(t, "test2", matched[0])
+		assert.Equal(t, "test2", matched[0].DispatcherName)
 	})
 }
+
+func TestEvaluateRules(t *testing.T) {
+	engine := dispatch.NewRuleEngine()
+	engine.SetRules([]dispatch.Rule{
+		{
+			ID:             "critical",
+			DispatcherName: "pager",
+			Match: []dispatch.RuleMatch{
+				{TagName: "severity", Operator: dispatch.EQUALS, Value: "critical"},
+			},
+		},
+		{
+			ID:             "database",
+			DispatcherName: "mail",
+			Match: []dispatch.RuleMatch{
+				{TagName: "severity", Operator: dispatch.EQUALS, Value: "warning"},
+				{TagName: "service", Operator: dispatch.EQUALS, Value: "database"},
+			},
+		},
+	})
+
+	msg := createTestMessage(t, map[string]string{"severity": "warning"})
+	evaluations, err := engine.EvaluateRules(context.Background(), msg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []dispatch.RuleEvaluation{
+		{
+			RuleID:         "critical",
+			DispatcherName: "pager",
+			Reason:         "no condition matched",
+			Conditions: []dispatch.ConditionResult{
+				{
+					RuleMatch: dispatch.RuleMatch{TagName: "severity", Operator: dispatch.EQUALS, Value: "critical"},
+					Actual:    "warning",
+					Reason:    "'warning' is not eq 'critical'",
+				},
+			},
+		},
+		{
+			RuleID:         "database",
+			DispatcherName: "mail",
+			Reason:         "a required tag is missing",
+			Conditions: []dispatch.ConditionResult{
+				{
+					RuleMatch: dispatch.RuleMatch{TagName: "severity", Operator: dispatch.EQUALS, Value: "warning"},
+					Actual:    "warning",
+					Matched:   true,
+				},
+				{
+					RuleMatch:  dispatch.RuleMatch{TagName: "service", Operator: dispatch.EQUALS, Value: "database"},
+					TagMissing: true,
+					Reason:     "tag 'service' is missing",
+				},
+			},
+		},
+	}, evaluations)
+}
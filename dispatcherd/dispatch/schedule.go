@@ -0,0 +1,185 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schedule restricts when a rule applies. A rule applies if the current day, time and date match; conditions which
+// are left empty always match.
+type Schedule struct {
+	// IANA time zone the schedule is evaluated in, UTC if empty
+	TimeZone TimeZone    `json:"timeZone"`
+	Days     []Weekday   `json:"days"`
+	Times    []TimeRange `json:"times"`
+	Dates    []DateRange `json:"dates"`
+}
+
+// TimeRange is a range of the time of day, ending exclusively. Ranges ending before they start span midnight.
+type TimeRange struct {
+	Start TimeOfDay `json:"start"`
+	End   TimeOfDay `json:"end"`
+}
+
+// DateRange is a range of dates, ending inclusively. Without an end, it covers only the start date.
+type DateRange struct {
+	Start Date `json:"start"`
+	End   Date `json:"end"`
+}
+
+func (s *Schedule) IsActive(now time.Time) bool {
+	local := now.In(s.TimeZone.Location())
+
+	if len(s.Times) == 0 && !s.onDay(local.Weekday()) {
+		return false
+	}
+
+	// the days apply to the day a time range starts, so "fri" with 22:00 to 06:00 covers the night into saturday
+	if len(s.Times) > 0 && !slices.ContainsFunc(s.Times, func(r TimeRange) bool {
+		return r.Contains(local) && s.onDay(r.startDay(local))
+	}) {
+		return false
+	}
+
+	if len(s.Dates) > 0 && !slices.ContainsFunc(s.Dates, func(r DateRange) bool { return r.Contains(local) }) {
+		return false
+	}
+
+	return true
+}
+
+func (s *Schedule) onDay(day time.Weekday) bool {
+	return len(s.Days) == 0 || slices.Contains(s.Days, Weekday(day))
+}
+
+// startDay is the day on which the range containing t started, the day before for the part after midnight.
+func (r TimeRange) startDay(t time.Time) time.Weekday {
+	if r.Start > r.End && TimeOfDay(t.Hour()*60+t.Minute()) < r.End {
+		return t.AddDate(0, 0, -1).Weekday()
+	}
+
+	return t.Weekday()
+}
+
+func (r TimeRange) Contains(t time.Time) bool {
+	minute := TimeOfDay(t.Hour()*60 + t.Minute())
+	if r.Start <= r.End {
+		return r.Start <= minute && minute < r.End
+	}
+
+	return minute >= r.Start || minute < r.End
+}
+
+func (r DateRange) Contains(t time.Time) bool {
+	date := Date(t.Format(time.DateOnly))
+	end := r.End
+	if end == "" {
+		end = r.Start
+	}
+
+	// dates in ISO format compare like strings
+	return r.Start <= date && date <= end
+}
+
+// TimeZone is a time.Location written as its IANA name, such as "Europe/Berlin", in configs.
+type TimeZone struct {
+	location *time.Location
+}
+
+func (z TimeZone) Location() *time.Location {
+	if z.location == nil {
+		return time.UTC
+	}
+
+	return z.location
+}
+
+func (z TimeZone) MarshalJSON() ([]byte, error) {
+	return json.Marshal(z.Location().String())
+}
+
+func (z *TimeZone) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("time zone must be a string such as \"Europe/Berlin\": %w", err)
+	}
+
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return err
+	}
+	z.location = location
+
+	return nil
+}
+
+// Weekday is a time.Weekday written as its name, such as "mon" or "monday", in configs.
+type Weekday time.Weekday
+
+func (d Weekday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(time.Weekday(d).String()[:3]))
+}
+
+func (d *Weekday) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("weekday must be a string such as \"mon\": %w", err)
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if strings.EqualFold(value, name) || strings.EqualFold(value, name[:3]) {
+			*d = Weekday(day)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown weekday '%s'", value)
+}
+
+// TimeOfDay is the number of minutes since midnight, written as "15:04" in configs. "24:00" denotes the end of a day.
+type TimeOfDay int
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%02d:%02d", t/60, t%60))
+}
+
+func (t *TimeOfDay) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("time of day must be a string such as \"09:30\": %w", err)
+	}
+
+	if value == "24:00" {
+		*t = TimeOfDay(24 * 60)
+		return nil
+	}
+
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return fmt.Errorf("invalid time of day '%s', expected HH:MM: %w", value, err)
+	}
+	*t = TimeOfDay(parsed.Hour()*60 + parsed.Minute())
+
+	return nil
+}
+
+// Date is a calendar date written as "2006-01-02" in configs.
+type Date string
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("date must be a string such as \"2025-12-24\": %w", err)
+	}
+
+	if _, err := time.Parse(time.DateOnly, value); err != nil {
+		return err
+	}
+	*d = Date(value)
+
+	return nil
+}
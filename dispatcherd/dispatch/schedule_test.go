@@ -0,0 +1,108 @@
+package dispatch_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSchedule(t *testing.T, scheduleJSON string) *dispatch.Schedule {
+	t.Helper()
+
+	var schedule dispatch.Schedule
+	require.NoError(t, json.Unmarshal([]byte(scheduleJSON), &schedule))
+	return &schedule
+}
+
+func TestScheduleIsActive(t *testing.T) {
+	businessHours := parseSchedule(t, `{
+		"timeZone": "Europe/Berlin",
+		"days": ["mon", "tue", "wednesday", "thu", "fri"],
+		"times": [{"start": "09:00", "end": "17:00"}]
+	}`)
+	night := parseSchedule(t, `{"times": [{"start": "22:00", "end": "06:00"}]}`)
+	fridayNight := parseSchedule(t, `{"days": ["fri"], "times": [{"start": "22:00", "end": "06:00"}]}`)
+	holidays := parseSchedule(t, `{"dates": [{"start": "2025-12-24", "end": "2025-12-26"}, {"start": "2025-12-31"}]}`)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		schedule *dispatch.Schedule
+		now      time.Time
+		expected bool
+	}{
+		{"business hours", businessHours, time.Date(2025, 11, 3, 9, 0, 0, 0, berlin), true},
+		{"business hours in other zone", businessHours, time.Date(2025, 11, 3, 8, 30, 0, 0, time.UTC), true},
+		{"after business hours", businessHours, time.Date(2025, 11, 3, 17, 0, 0, 0, berlin), false},
+		{"weekend", businessHours, time.Date(2025, 11, 1, 12, 0, 0, 0, berlin), false},
+		{"night before midnight", night, time.Date(2025, 11, 3, 23, 0, 0, 0, time.UTC), true},
+		{"night after midnight", night, time.Date(2025, 11, 3, 5, 59, 0, 0, time.UTC), true},
+		{"day", night, time.Date(2025, 11, 3, 6, 0, 0, 0, time.UTC), false},
+		{"friday night", fridayNight, time.Date(2025, 11, 7, 23, 0, 0, 0, time.UTC), true},
+		{"friday night after midnight", fridayNight, time.Date(2025, 11, 8, 1, 0, 0, 0, time.UTC), true},
+		{"thursday night after midnight", fridayNight, time.Date(2025, 11, 7, 1, 0, 0, 0, time.UTC), false},
+		{"saturday night", fridayNight, time.Date(2025, 11, 8, 23, 0, 0, 0, time.UTC), false},
+		{"within date range", holidays, time.Date(2025, 12, 26, 23, 59, 0, 0, time.UTC), true},
+		{"single date", holidays, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{"outside date range", holidays, time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.schedule.IsActive(tt.now))
+		})
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	for _, scheduleJSON := range []string{
+		`{"timeZone": "Mars/Olympus"}`,
+		`{"days": ["someday"]}`,
+		`{"times": [{"start": "9am", "end": "17:00"}]}`,
+		`{"times": [{"start": "09:00", "end": "24:30"}]}`,
+		`{"dates": [{"start": "2025-13-01"}]}`,
+	} {
+		var schedule dispatch.Schedule
+		assert.Error(t, json.Unmarshal([]byte(scheduleJSON), &schedule), scheduleJSON)
+	}
+}
+
+func TestRuleSchedule(t *testing.T) {
+	now := time.Date(2025, 11, 3, 10, 0, 0, 0, time.UTC)
+	engine := dispatch.NewRuleEngineWithClock(func() time.Time { return now })
+
+	match := []dispatch.RuleMatch{{TagName: "severity", Operator: dispatch.EQUALS, Value: "warning"}}
+	engine.SetRules([]dispatch.Rule{
+		{
+			ID:             "mail during business hours",
+			DispatcherName: "mail",
+			Match:          match,
+			Schedule:       parseSchedule(t, `{"times": [{"start": "09:00", "end": "17:00"}]}`),
+		},
+		{
+			ID:             "log at night",
+			DispatcherName: "log",
+			Match:          match,
+			Schedule:       parseSchedule(t, `{"times": [{"start": "17:00", "end": "09:00"}]}`),
+		},
+	})
+
+	msg := createTestMessage(t, map[string]string{"severity": "warning"})
+	matched, err := engine.ProcessMessage(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+	assert.Equal(t, "mail", matched[0].DispatcherName)
+
+	now = time.Date(2025, 11, 3, 22, 0, 0, 0, time.UTC)
+	matched, err = engine.ProcessMessage(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+	assert.Equal(t, "log", matched[0].DispatcherName)
+}
@@ -0,0 +1,45 @@
+package dispatch
+
+import "time"
+
+type SilenceStatus string
+
+const (
+	SilenceActive  SilenceStatus = "active"
+	SilencePending SilenceStatus = "pending"
+)
+
+// Silence mutes the messages matching all of its matchers between its start and end.
+type Silence struct {
+	ID        string      `json:"id"`
+	Matchers  []RuleMatch `json:"matchers"`
+	StartsAt  time.Time   `json:"startsAt"`
+	EndsAt    time.Time   `json:"endsAt"`
+	CreatedBy string      `json:"createdBy"`
+	// client whose messages are muted, all messages are muted if empty
+	Owner     string        `json:"owner,omitempty"`
+	Comment   string        `json:"comment"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Status    SilenceStatus `json:"status"`
+	// number of messages muted so far
+	Silenced int `json:"silenced"`
+}
+
+func (s Silence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Matches returns whether the silence is active and mutes the message.
+func (s Silence) Matches(msg *Message, now time.Time) bool {
+	if !s.IsActive(now) {
+		return false
+	}
+
+	for _, matcher := range s.Matchers {
+		if !matcher.Matches(msg.Tags) {
+			return false
+		}
+	}
+
+	return true
+}
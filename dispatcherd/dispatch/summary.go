@@ -0,0 +1,66 @@
+package dispatch
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// NewSummaryMessage combines several messages into one, listing their titles. Tags shared by all messages are kept.
+func NewSummaryMessage(title string, messages []*Message) *Message {
+	return NewGroupedSummaryMessage(title, messages, "")
+}
+
+// NewGroupedSummaryMessage combines several messages into one, listing their titles grouped by the value of a tag.
+func NewGroupedSummaryMessage(title string, messages []*Message, groupBy string) *Message {
+	var body strings.Builder
+
+	if groupBy == "" {
+		writeTitles(&body, messages)
+	} else {
+		groups := make(map[string][]*Message)
+		for _, msg := range messages {
+			groups[msg.Tags[groupBy]] = append(groups[msg.Tags[groupBy]], msg)
+		}
+
+		for i, value := range slices.Sorted(maps.Keys(groups)) {
+			if i > 0 {
+				body.WriteString("\n")
+			}
+			body.WriteString(fmt.Sprintf("%s=%s (%d)\n", groupBy, value, len(groups[value])))
+			writeTitles(&body, groups[value])
+		}
+	}
+
+	return NewMessage(title, body.String(), commonTags(messages))
+}
+
+func writeTitles(body *strings.Builder, messages []*Message) {
+	for _, msg := range messages {
+		body.WriteString(fmt.Sprintf("- %s\n", msg.Title))
+	}
+}
+
+func commonTags(messages []*Message) map[string]string {
+	if len(messages) == 0 || messages[0].Tags == nil {
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for name, value := range messages[0].Tags {
+		shared := true
+		for _, msg := range messages[1:] {
+			if other, ok := msg.Tags[name]; !ok || other != value {
+				shared = false
+				break
+			}
+		}
+
+		if shared {
+			tags[name] = value
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,37 @@
+package dispatch_test
+
+import (
+	"dispatcherd/dispatch"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSummaryMessage(t *testing.T) {
+	messages := []*dispatch.Message{
+		dispatch.NewMessage("Disk full", "disk /dev/sda1 is full", map[string]string{"host": "a", "severity": "high"}),
+		dispatch.NewMessage("Load high", "load is 12", map[string]string{"host": "a", "severity": "low"}),
+	}
+
+	summary := dispatch.NewSummaryMessage("2 messages", messages)
+
+	assert.NotEmpty(t, summary.ID)
+	assert.Equal(t, "2 messages", summary.Title)
+	assert.Equal(t, "- Disk full\n- Load high\n", summary.Message)
+	assert.Equal(t, map[string]string{"host": "a"}, summary.Tags)
+}
+
+func TestNewGroupedSummaryMessage(t *testing.T) {
+	messages := []*dispatch.Message{
+		dispatch.NewMessage("Disk full", "", map[string]string{"host": "b"}),
+		dispatch.NewMessage("Load high", "", map[string]string{"host": "a"}),
+		dispatch.NewMessage("Swap full", "", map[string]string{"host": "b"}),
+		dispatch.NewMessage("Clock skew", "", nil),
+	}
+
+	summary := dispatch.NewGroupedSummaryMessage("4 messages", messages, "host")
+
+	assert.Equal(t, "host= (1)\n- Clock skew\n\nhost=a (1)\n- Load high\n\nhost=b (2)\n- Disk full\n- Swap full\n",
+		summary.Message)
+	assert.Empty(t, summary.Tags)
+}
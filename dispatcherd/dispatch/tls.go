@@ -0,0 +1,61 @@
+package dispatch
+
+import (
+	"crypto/tls"
+	"dispatcherd/tlsutil"
+)
+
+// tlsClientConfig configures the TLS connections of dispatchers, which keep their own connections to the receiver.
+type tlsClientConfig struct {
+	caFile             string
+	certFile           string
+	keyFile            string
+	serverName         string
+	insecureSkipVerify bool
+}
+
+var tlsClientConfigSchema = map[string]interface{}{
+	"caFile":             "omitempty,file",
+	"certFile":           "omitempty,file",
+	"keyFile":            "omitempty,file",
+	"serverName":         "omitempty,hostname",
+	"insecureSkipVerify": "omitempty,boolean",
+}
+
+func parseTLSClientConfig(config map[string]interface{}) tlsClientConfig {
+	var c tlsClientConfig
+	c.caFile, _ = config["caFile"].(string)
+	c.certFile, _ = config["certFile"].(string)
+	c.keyFile, _ = config["keyFile"].(string)
+	c.serverName, _ = config["serverName"].(string)
+	c.insecureSkipVerify, _ = config["insecureSkipVerify"].(bool)
+	return c
+}
+
+// load reads the certificates on every connect, so that rotated certificates are picked up by new connections.
+func (c tlsClientConfig) load() (*tls.Config, error) {
+	//nolint:exhaustruct // pkg defaults are fine
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.serverName,
+		InsecureSkipVerify: c.insecureSkipVerify, //nolint:gosec // explicitly configured for self-signed receivers
+	}
+
+	if c.caFile != "" {
+		pool, err := tlsutil.LoadCertPool(c.caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if c.certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
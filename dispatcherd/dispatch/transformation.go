@@ -0,0 +1,59 @@
+package dispatch
+
+import (
+	"maps"
+	"slices"
+)
+
+// Transformation shapes a message before it is delivered by a rule. The steps are applied in the order of the fields.
+type Transformation struct {
+	// copies the value of the source tag to the target tag, keyed by source
+	CopyTags map[string]string `json:"copyTags"`
+	// renames tags, keyed by their current name
+	RenameTags map[string]string `json:"renameTags"`
+	RemoveTags []string          `json:"removeTags"`
+	SetTags    map[string]string `json:"setTags"`
+	// prepended to the title, e.g. "[PROD] "
+	TitlePrefix string `json:"titlePrefix"`
+	// truncates longer message bodies to this many characters, disabled if zero
+	MaxMessageLength int `json:"maxMessageLength"`
+}
+
+// Apply returns a transformed copy of the message, leaving the original untouched. Without transformation, the
+// message itself is returned.
+func (t *Transformation) Apply(msg *Message) *Message {
+	if t == nil {
+		return msg
+	}
+
+	transformed := msg.Clone()
+	if transformed.Tags == nil {
+		transformed.Tags = make(map[string]string)
+	}
+
+	// iterate in a stable order, so that overlapping names are handled the same on every message
+	for _, source := range slices.Sorted(maps.Keys(t.CopyTags)) {
+		if value, ok := msg.Tags[source]; ok {
+			transformed.Tags[t.CopyTags[source]] = value
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(t.RenameTags)) {
+		if value, ok := transformed.Tags[name]; ok {
+			delete(transformed.Tags, name)
+			transformed.Tags[t.RenameTags[name]] = value
+		}
+	}
+
+	for _, name := range t.RemoveTags {
+		delete(transformed.Tags, name)
+	}
+
+	maps.Copy(transformed.Tags, t.SetTags)
+
+	transformed.Title = t.TitlePrefix + transformed.Title
+
+	transformed.Message = truncate(transformed.Message, t.MaxMessageLength)
+
+	return transformed
+}
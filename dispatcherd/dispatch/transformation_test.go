@@ -0,0 +1,52 @@
+package dispatch_test
+
+import (
+	"dispatcherd/dispatch"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformationApply(t *testing.T) {
+	msg := dispatch.NewMessage("Disk full", "Usage is at 99% on /var", map[string]string{
+		"host":     "db-1",
+		"password": "secret",
+		"env":      "prod",
+	})
+
+	transformation := &dispatch.Transformation{
+		CopyTags:         map[string]string{"host": "instance"},
+		RenameTags:       map[string]string{"env": "environment"},
+		RemoveTags:       []string{"password"},
+		SetTags:          map[string]string{"team": "dba"},
+		TitlePrefix:      "[PROD] ",
+		MaxMessageLength: 10,
+	}
+	transformed := transformation.Apply(msg)
+
+	assert.Equal(t, msg.ID, transformed.ID)
+	assert.Equal(t, "[PROD] Disk full", transformed.Title)
+	assert.Equal(t, "Usage is …", transformed.Message)
+	assert.Equal(t, map[string]string{
+		"host":        "db-1",
+		"instance":    "db-1",
+		"environment": "prod",
+		"team":        "dba",
+	}, transformed.Tags)
+
+	// the original message is untouched
+	assert.Equal(t, "Disk full", msg.Title)
+	assert.Equal(t, "Usage is at 99% on /var", msg.Message)
+	assert.Len(t, msg.Tags, 3)
+
+	t.Run("without transformation", func(t *testing.T) {
+		var none *dispatch.Transformation
+		assert.Same(t, msg, none.Apply(msg))
+	})
+
+	t.Run("message without tags", func(t *testing.T) {
+		transformed := transformation.Apply(dispatch.NewMessage("title", "short", nil))
+		assert.Equal(t, map[string]string{"team": "dba"}, transformed.Tags)
+		assert.Equal(t, "short", transformed.Message)
+	})
+}
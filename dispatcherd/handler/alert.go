@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"dispatcherd/logging"
+	"dispatcherd/service"
+	"log/slog"
+	"net/http"
+)
+
+type AlertHandler struct {
+	logger     *slog.Logger
+	messageSvc service.MessageService
+}
+
+func NewAlertHandler(msgSvc service.MessageService) *AlertHandler {
+	return &AlertHandler{
+		logger:     logging.GetLogger(logging.API),
+		messageSvc: msgSvc,
+	}
+}
+
+func (h *AlertHandler) HandleList(w http.ResponseWriter, r *http.Request) error {
+	alerts, err := h.messageSvc.ListAlerts(r.Context())
+	if err != nil {
+		return OtherError(err)
+	}
+
+	return RespondMany(w, r, alerts)
+}
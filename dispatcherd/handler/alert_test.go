@@ -0,0 +1,52 @@
+package handler_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/handler"
+	"dispatcherd/test"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListAlerts(t *testing.T) {
+	firedAt := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	alert := dispatch.Alert{
+		Key:         "disk-a",
+		Title:       "disk full",
+		Message:     "message",
+		MessageID:   "id",
+		Dispatchers: []string{"ops"},
+		FiredAt:     firedAt,
+		UpdatedAt:   firedAt,
+	}
+	mockSvc := &MockMessageService{
+		ListAlertsFunc: func(ctx context.Context) ([]dispatch.Alert, error) {
+			return []dispatch.Alert{alert}, nil
+		},
+	}
+	h := handler.NewAlertHandler(mockSvc)
+
+	res := test.NewTestRunner(h.HandleList).Run(t).ExpectNoError().ExpectStatusCode(http.StatusOK)
+	test.AssertJSON(t, res.RR.Body.String(), handler.ArrayDataResponse[dispatch.Alert]{
+		APIVersion: 1,
+		Data: handler.APIComponentArray[dispatch.Alert]{
+			TotalItems:       1,
+			Items:            []dispatch.Alert{alert},
+			CurrentItemCount: 1,
+		},
+	})
+}
+
+func TestListAlertsError(t *testing.T) {
+	mockSvc := &MockMessageService{
+		ListAlertsFunc: func(ctx context.Context) ([]dispatch.Alert, error) {
+			return nil, errors.New("test")
+		},
+	}
+	h := handler.NewAlertHandler(mockSvc)
+
+	test.NewTestRunner(h.HandleList).Run(t).ExpectAPIError(http.StatusInternalServerError)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"dispatcherd/auth"
 	"dispatcherd/dispatch"
 	"dispatcherd/logging"
 	"dispatcherd/service"
@@ -12,9 +13,13 @@ import (
 )
 
 type PostMessageRequestBody struct {
-	Title   string            `json:"title" validate:"required"`
-	Message string            `json:"message" validate:"required"`
-	Tags    map[string]string `json:"tags"`
+	Title    string            `json:"title" validate:"required"`
+	Message  string            `json:"message" validate:"required"`
+	Tags     map[string]string `json:"tags"`
+	DedupKey string            `json:"dedupKey"`
+	// identifies a stateful alert, which is open until a message with the same key and status resolved arrives
+	Key    string                 `json:"key" validate:"required_with=Status"`
+	Status dispatch.MessageStatus `json:"status" validate:"omitempty,oneof=firing resolved"`
 }
 
 type PostMessageResponse struct {
@@ -36,8 +41,15 @@ func NewDispatchHandler(msgSvc service.MessageService) *MessageHandler {
 }
 
 func (h *MessageHandler) HandlePost(w http.ResponseWriter, r *http.Request) error {
-	var body PostMessageRequestBody
-	if err := ParseAndValidateBody(&body, r, h.validate); err != nil {
+	message, err := h.parseMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.messageSvc.QueueMessage(r.Context(), message); err != nil {
+		if errors.Is(err, service.ErrOwnedByOtherClient) {
+			return Forbidden(err.Error())
+		}
 		var apiErr APIError
 		if errors.As(err, &apiErr) {
 			return apiErr
@@ -45,17 +57,72 @@ func (h *MessageHandler) HandlePost(w http.ResponseWriter, r *http.Request) erro
 		return OtherError(err)
 	}
 
-	message := dispatch.NewMessage(body.Title, body.Message, body.Tags)
+	return RespondOne(w, r, PostMessageResponse{
+		MessageID: message.ID,
+	})
+}
 
-	if err := h.messageSvc.QueueMessage(r.Context(), message); err != nil {
+// HandleEvaluate explains how a message would be dispatched, without dispatching it.
+func (h *MessageHandler) HandleEvaluate(w http.ResponseWriter, r *http.Request) error {
+	message, err := h.parseMessage(r)
+	if err != nil {
+		return err
+	}
+
+	evaluation, err := h.messageSvc.EvaluateMessage(r.Context(), message)
+	if err != nil {
+		return OtherError(err)
+	}
+
+	return RespondOne(w, r, evaluation)
+}
+
+// parseMessage creates a message from the request body, applying the policy of the client.
+func (h *MessageHandler) parseMessage(r *http.Request) (*dispatch.Message, error) {
+	var body PostMessageRequestBody
+	if err := ParseAndValidateBody(&body, r, h.validate); err != nil {
 		var apiErr APIError
 		if errors.As(err, &apiErr) {
-			return apiErr
+			return nil, apiErr
+		}
+		return nil, OtherError(err)
+	}
+
+	tags := body.Tags
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+		policyTags, err := identity.Policy.ApplyTags(tags)
+		if err != nil {
+			return nil, Forbidden(err.Error())
+		}
+		tags = policyTags
+	}
+
+	message := dispatch.NewMessage(body.Title, body.Message, tags)
+	message.DedupKey = body.DedupKey
+	message.Key = body.Key
+	message.Status = body.Status
+	if message.Key != "" && message.Status == "" {
+		message.Status = dispatch.StatusFiring
+	}
+
+	return message, nil
+}
+
+// HandleAck acknowledges a message, stopping its pending escalation.
+func (h *MessageHandler) HandleAck(w http.ResponseWriter, r *http.Request) error {
+	messageID := r.PathValue("id")
+
+	if err := h.messageSvc.AcknowledgeMessage(r.Context(), messageID); err != nil {
+		if errors.Is(err, service.ErrEscalationNotFound) {
+			return NotFound("escalation", messageID)
+		}
+		if errors.Is(err, service.ErrOwnedByOtherClient) {
+			return Forbidden(err.Error())
 		}
 		return OtherError(err)
 	}
 
 	return RespondOne(w, r, PostMessageResponse{
-		MessageID: message.ID,
+		MessageID: messageID,
 	})
 }
@@ -2,8 +2,10 @@ package handler_test
 
 import (
 	"context"
+	"dispatcherd/auth"
 	"dispatcherd/dispatch"
 	"dispatcherd/handler"
+	"dispatcherd/service"
 	"dispatcherd/test"
 	"errors"
 	"net/http"
@@ -67,3 +69,169 @@ func TestPostMessageAPIError(t *testing.T) {
 	runner.WithBodyString(body).Run(t).ExpectAPIError(http.StatusNotFound)
 	assert.Len(t, mockSvc.QueueMessageCalls(), 1)
 }
+
+func TestPostMessageClientPolicy(t *testing.T) {
+	identity := &auth.Identity{
+		Name: "team-a",
+		Policy: &auth.Policy{
+			AllowedTagValues: map[string][]string{"team": {"a"}},
+			ForcedTags:       map[string]string{"source": "team-a"},
+		},
+	}
+
+	t.Run("applies forced tags", func(t *testing.T) {
+		mockSvc := &MockMessageService{
+			QueueMessageFunc: func(ctx context.Context, msg *dispatch.Message) error {
+				return nil
+			},
+		}
+		h := handler.NewDispatchHandler(mockSvc)
+
+		body := `{"title": "Test Title", "message": "Test Message", "tags": {"team": "a"}}`
+		runner := test.NewTestRunner(h.HandlePost)
+		runner.WithBodyString(body).WithIdentity(identity).Run(t).ExpectNoError().ExpectStatusCode(http.StatusOK)
+		assert.Len(t, mockSvc.QueueMessageCalls(), 1)
+		assert.Equal(t, map[string]string{"team": "a", "source": "team-a"}, mockSvc.QueueMessageCalls()[0].Message.Tags)
+	})
+
+	t.Run("rejects forged tags", func(t *testing.T) {
+		mockSvc := &MockMessageService{
+			QueueMessageFunc: func(ctx context.Context, msg *dispatch.Message) error {
+				return nil
+			},
+		}
+		h := handler.NewDispatchHandler(mockSvc)
+
+		body := `{"title": "Test Title", "message": "Test Message", "tags": {"team": "b"}}`
+		runner := test.NewTestRunner(h.HandlePost)
+		runner.WithBodyString(body).WithIdentity(identity).Run(t).ExpectAPIError(http.StatusForbidden)
+		assert.Len(t, mockSvc.QueueMessageCalls(), 0)
+	})
+}
+
+func TestPostMessageDedupKey(t *testing.T) {
+	mockSvc := &MockMessageService{
+		QueueMessageFunc: func(ctx context.Context, msg *dispatch.Message) error {
+			return nil
+		},
+	}
+	h := handler.NewDispatchHandler(mockSvc)
+
+	body := `{"title": "Test Title", "message": "Test Message", "dedupKey": "disk-full"}`
+	runner := test.NewTestRunner(h.HandlePost)
+	runner.WithBodyString(body).Run(t).ExpectNoError().ExpectStatusCode(http.StatusOK)
+	assert.Len(t, mockSvc.QueueMessageCalls(), 1)
+	assert.Equal(t, "disk-full", mockSvc.QueueMessageCalls()[0].Message.DedupKey)
+}
+
+func TestPostMessageAlert(t *testing.T) {
+	newMockSvc := func() *MockMessageService {
+		return &MockMessageService{
+			QueueMessageFunc: func(ctx context.Context, msg *dispatch.Message) error {
+				return nil
+			},
+		}
+	}
+
+	t.Run("key without status is firing", func(t *testing.T) {
+		mockSvc := newMockSvc()
+		h := handler.NewDispatchHandler(mockSvc)
+
+		body := `{"title": "Test Title", "message": "Test Message", "key": "disk-full"}`
+		runner := test.NewTestRunner(h.HandlePost)
+		runner.WithBodyString(body).Run(t).ExpectNoError().ExpectStatusCode(http.StatusOK)
+		assert.Len(t, mockSvc.QueueMessageCalls(), 1)
+		assert.Equal(t, "disk-full", mockSvc.QueueMessageCalls()[0].Message.Key)
+		assert.Equal(t, dispatch.StatusFiring, mockSvc.QueueMessageCalls()[0].Message.Status)
+	})
+
+	t.Run("resolves with key", func(t *testing.T) {
+		mockSvc := newMockSvc()
+		h := handler.NewDispatchHandler(mockSvc)
+
+		body := `{"title": "Test Title", "message": "Test Message", "key": "disk-full", "status": "resolved"}`
+		runner := test.NewTestRunner(h.HandlePost)
+		runner.WithBodyString(body).Run(t).ExpectNoError().ExpectStatusCode(http.StatusOK)
+		assert.Equal(t, dispatch.StatusResolved, mockSvc.QueueMessageCalls()[0].Message.Status)
+	})
+
+	t.Run("rejects status without key", func(t *testing.T) {
+		mockSvc := newMockSvc()
+		h := handler.NewDispatchHandler(mockSvc)
+
+		body := `{"title": "Test Title", "message": "Test Message", "status": "resolved"}`
+		runner := test.NewTestRunner(h.HandlePost)
+		runner.WithBodyString(body).Run(t).ExpectAPIError(http.StatusBadRequest)
+		assert.Len(t, mockSvc.QueueMessageCalls(), 0)
+	})
+
+	t.Run("rejects unknown status", func(t *testing.T) {
+		mockSvc := newMockSvc()
+		h := handler.NewDispatchHandler(mockSvc)
+
+		body := `{"title": "Test Title", "message": "Test Message", "key": "disk-full", "status": "open"}`
+		runner := test.NewTestRunner(h.HandlePost)
+		runner.WithBodyString(body).Run(t).ExpectAPIError(http.StatusBadRequest)
+		assert.Len(t, mockSvc.QueueMessageCalls(), 0)
+	})
+}
+
+func TestAckMessage(t *testing.T) {
+	t.Run("acknowledges pending escalation", func(t *testing.T) {
+		mockSvc := &MockMessageService{
+			AcknowledgeMessageFunc: func(ctx context.Context, messageID string) error {
+				return nil
+			},
+		}
+		h := handler.NewDispatchHandler(mockSvc)
+
+		res := test.NewTestRunner(h.HandleAck).WithPath("id", "123").Run(t).ExpectNoError().
+			ExpectStatusCode(http.StatusOK)
+		test.AssertSingleAPIResponse(res, handler.PostMessageResponse{MessageID: "123"})
+	})
+
+	t.Run("fails without pending escalation", func(t *testing.T) {
+		mockSvc := &MockMessageService{
+			AcknowledgeMessageFunc: func(ctx context.Context, messageID string) error {
+				return service.ErrEscalationNotFound
+			},
+		}
+		h := handler.NewDispatchHandler(mockSvc)
+
+		test.NewTestRunner(h.HandleAck).WithPath("id", "123").Run(t).ExpectAPIError(http.StatusNotFound)
+	})
+
+	t.Run("fails for escalation of other client", func(t *testing.T) {
+		mockSvc := &MockMessageService{
+			AcknowledgeMessageFunc: func(ctx context.Context, messageID string) error {
+				return service.ErrOwnedByOtherClient
+			},
+		}
+		h := handler.NewDispatchHandler(mockSvc)
+
+		test.NewTestRunner(h.HandleAck).WithPath("id", "123").Run(t).ExpectAPIError(http.StatusForbidden)
+	})
+}
+
+func TestEvaluateMessage(t *testing.T) {
+	evaluation := dispatch.Evaluation{
+		Rules:              []dispatch.RuleEvaluation{{RuleID: "critical", DispatcherName: "pager", Matched: true}},
+		Dispatchers:        []string{"pager"},
+		EscalationPolicies: []string{},
+	}
+	mockSvc := &MockMessageService{
+		EvaluateMessageFunc: func(ctx context.Context, message *dispatch.Message) (dispatch.Evaluation, error) {
+			return evaluation, nil
+		},
+		QueueMessageFunc: func(ctx context.Context, msg *dispatch.Message) error {
+			return nil
+		},
+	}
+	h := handler.NewDispatchHandler(mockSvc)
+
+	body := `{"title": "Test Title", "message": "Test Message", "tags": {"severity": "critical"}}`
+	res := test.NewTestRunner(h.HandleEvaluate).WithBodyString(body).Run(t).ExpectNoError().
+		ExpectStatusCode(http.StatusOK)
+	test.AssertSingleAPIResponse(res, evaluation)
+	assert.Len(t, mockSvc.QueueMessageCalls(), 0)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"dispatcherd/service"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type PostSilenceRequestBody struct {
+	Matchers []dispatch.RuleMatch `json:"matchers" validate:"required,min=1,dive"`
+	// defaults to now
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt" validate:"required"`
+	CreatedBy string    `json:"createdBy" validate:"required"`
+	Comment   string    `json:"comment"`
+}
+
+type SilenceHandler struct {
+	logger     *slog.Logger
+	validate   *validator.Validate
+	messageSvc service.MessageService
+}
+
+func NewSilenceHandler(msgSvc service.MessageService) *SilenceHandler {
+	return &SilenceHandler{
+		logger:     logging.GetLogger(logging.API),
+		validate:   validator.New(validator.WithRequiredStructEnabled()),
+		messageSvc: msgSvc,
+	}
+}
+
+func (h *SilenceHandler) HandlePost(w http.ResponseWriter, r *http.Request) error {
+	var body PostSilenceRequestBody
+	if err := ParseAndValidateBody(&body, r, h.validate); err != nil {
+		var apiErr APIError
+		if errors.As(err, &apiErr) {
+			return apiErr
+		}
+		return OtherError(err)
+	}
+
+	if !body.EndsAt.After(time.Now()) || (!body.StartsAt.IsZero() && !body.EndsAt.After(body.StartsAt)) {
+		return InvalidRequest("silence must end in the future and after its start", nil)
+	}
+
+	silence, err := h.messageSvc.CreateSilence(r.Context(), dispatch.Silence{
+		Matchers:  body.Matchers,
+		StartsAt:  body.StartsAt,
+		EndsAt:    body.EndsAt,
+		CreatedBy: body.CreatedBy,
+		Comment:   body.Comment,
+	})
+	if err != nil {
+		return OtherError(err)
+	}
+
+	return RespondOneCreated(w, r, silence)
+}
+
+func (h *SilenceHandler) HandleList(w http.ResponseWriter, r *http.Request) error {
+	silences, err := h.messageSvc.ListSilences(r.Context())
+	if err != nil {
+		return OtherError(err)
+	}
+
+	return RespondMany(w, r, silences)
+}
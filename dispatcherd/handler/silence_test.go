@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/handler"
+	"dispatcherd/test"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockSilenceService() *MockMessageService {
+	mockSvc := &MockMessageService{}
+	mockSvc.CreateSilenceFunc = func(ctx context.Context, silence dispatch.Silence) (dispatch.Silence, error) {
+		silence.ID = "id"
+		mockSvc.ListSilencesFunc = func(ctx context.Context) ([]dispatch.Silence, error) {
+			return []dispatch.Silence{silence}, nil
+		}
+		return silence, nil
+	}
+
+	return mockSvc
+}
+
+func TestPostSilence(t *testing.T) {
+	t.Run("creates silence", func(t *testing.T) {
+		mockSvc := newMockSilenceService()
+		h := handler.NewSilenceHandler(mockSvc)
+
+		endsAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		body := handler.PostSilenceRequestBody{
+			Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-1"}},
+			EndsAt:    endsAt,
+			CreatedBy: "operator",
+			Comment:   "maintenance",
+		}
+		test.NewTestRunner(h.HandlePost).WithBody(body).Run(t).ExpectNoError().ExpectStatusCode(http.StatusCreated)
+
+		silences, err := mockSvc.ListSilences(context.Background())
+		require.NoError(t, err)
+		require.Len(t, silences, 1)
+		assert.Equal(t, body.Matchers, silences[0].Matchers)
+		assert.Equal(t, endsAt, silences[0].EndsAt)
+		assert.Equal(t, "operator", silences[0].CreatedBy)
+	})
+
+	t.Run("rejects silence in the past", func(t *testing.T) {
+		h := handler.NewSilenceHandler(newMockSilenceService())
+
+		body := handler.PostSilenceRequestBody{
+			Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-1"}},
+			EndsAt:    time.Now().Add(-time.Hour),
+			CreatedBy: "operator",
+		}
+		test.NewTestRunner(h.HandlePost).WithBody(body).Run(t).ExpectAPIError(http.StatusBadRequest)
+	})
+
+	t.Run("rejects unknown operator", func(t *testing.T) {
+		h := handler.NewSilenceHandler(newMockSilenceService())
+
+		body := handler.PostSilenceRequestBody{
+			Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: "like", Value: "db-1"}},
+			EndsAt:    time.Now().Add(time.Hour),
+			CreatedBy: "operator",
+		}
+		test.NewTestRunner(h.HandlePost).WithBody(body).Run(t).ExpectAPIError(http.StatusBadRequest)
+	})
+}
+
+func TestListSilences(t *testing.T) {
+	mockSvc := &MockMessageService{
+		ListSilencesFunc: func(ctx context.Context) ([]dispatch.Silence, error) {
+			return []dispatch.Silence{}, nil
+		},
+	}
+	h := handler.NewSilenceHandler(mockSvc)
+
+	res := test.NewTestRunner(h.HandleList).Run(t).ExpectNoError().ExpectStatusCode(http.StatusOK)
+	test.AssertJSON(t, res.RR.Body.String(), handler.ArrayDataResponse[dispatch.Silence]{
+		APIVersion: 1,
+		Data: handler.APIComponentArray[dispatch.Silence]{
+			Items: []dispatch.Silence{},
+		},
+	})
+}
@@ -115,6 +115,13 @@ func NotFound(objectType string, objectID string) APIError {
 	}
 }
 
+func Forbidden(message string) APIError {
+	return APIError{
+		StatusCode: http.StatusForbidden,
+		Message:    message,
+	}
+}
+
 func InvalidRequest(message string, validationError error) APIError {
 	errorMessage := message
 
@@ -174,7 +181,6 @@ func RespondOne[T any](w http.ResponseWriter, r *http.Request, data T) error {
 	return respondOneWithStatus(w, r, http.StatusOK, data)
 }
 
-//nolint:unused // will be used in the future
 func RespondOneCreated[T any](w http.ResponseWriter, r *http.Request, data T) error {
 	return respondOneWithStatus(w, r, http.StatusCreated, data)
 }
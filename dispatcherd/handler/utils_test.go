@@ -18,6 +18,11 @@ func TestNotFound(t *testing.T) {
 	assert.Equal(t, err.StatusCode, http.StatusNotFound)
 }
 
+func TestForbidden(t *testing.T) {
+	err := handler.Forbidden("message")
+	assert.Equal(t, err.StatusCode, http.StatusForbidden)
+}
+
 func TestInvalidRequest(t *testing.T) {
 	err := handler.InvalidRequest("message", nil)
 	assert.Equal(t, err.StatusCode, http.StatusBadRequest)
@@ -9,6 +9,7 @@ import (
 const (
 	FieldRequestID string = "requestId"
 	FieldMessageID string = "messageId"
+	FieldClientID  string = "clientId"
 	FieldError     string = "error"
 )
 
@@ -25,5 +26,9 @@ func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
 		r.AddAttrs(slog.String(FieldMessageID, val))
 	}
 
+	if val, ok := ctx.Value(dispatcherdContext.KeyClientID).(string); ok {
+		r.AddAttrs(slog.String(FieldClientID, val))
+	}
+
 	return h.Handler.Handle(ctx, r)
 }
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"dispatcherd/auth"
+	"dispatcherd/handler"
+	"dispatcherd/logging"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing API key")
+	ErrInvalidCredentials = errors.New("invalid API key")
+)
+
+type AuthMiddleware struct {
+	APIKeyHeader string
+	logger       *slog.Logger
+	keys         []auth.APIKey
+}
+
+func NewAPIKeyAuthMiddleware(keys []auth.APIKey) *AuthMiddleware {
+	return &AuthMiddleware{
+		APIKeyHeader: "X-API-Key",
+		logger:       logging.GetLogger(logging.Audit),
+		keys:         keys,
+	}
+}
+
+// OnRequest identifies the client by its API key or its TLS client certificate, if one is presented. Requests without
+// credentials are passed on anonymously, so that routes can decide with RequireScope whether authentication is needed.
+func (h *AuthMiddleware) OnRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presentedKey := h.extractKey(r)
+		if presentedKey == "" {
+			if key, ok := h.lookupCertificate(r); ok {
+				r = r.WithContext(auth.WithIdentity(r.Context(), key.Identity()))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := h.lookup(presentedKey)
+		if !ok {
+			h.logger.WarnContext(r.Context(), "authentication failed",
+				"src", r.RemoteAddr,
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+			respondUnauthorized(w, r, ErrInvalidCredentials)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), key.Identity())))
+	})
+}
+
+func (h *AuthMiddleware) extractKey(r *http.Request) string {
+	if key := r.Header.Get(h.APIKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return ""
+}
+
+func (h *AuthMiddleware) lookup(presentedKey string) (auth.APIKey, bool) {
+	presentedHash := []byte(auth.HashKey(presentedKey))
+
+	// compare against every key to not leak timing information about which keys exist
+	var found auth.APIKey
+	ok := false
+	for _, key := range h.keys {
+		if subtle.ConstantTimeCompare(presentedHash, []byte(key.KeyHash)) == 1 {
+			found = key
+			ok = true
+		}
+	}
+
+	return found, ok
+}
+
+// lookupCertificate finds the API key configured for the common name of the client certificate. Certificates without
+// a matching key stay anonymous.
+func (h *AuthMiddleware) lookupCertificate(r *http.Request) (auth.APIKey, bool) {
+	// only trust certificates verified against the client CA
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return auth.APIKey{}, false
+	}
+
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, key := range h.keys {
+		if key.CertificateCommonName != "" && key.CertificateCommonName == commonName {
+			return key, true
+		}
+	}
+
+	return auth.APIKey{}, false
+}
+
+func RequireScope(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := auth.IdentityFromContext(r.Context())
+			if !ok {
+				respondUnauthorized(w, r, ErrMissingCredentials)
+				return
+			}
+
+			if !identity.HasScope(scope) {
+				handler.RespondError(w, r, http.StatusForbidden, fmt.Errorf("missing scope %s", scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func respondUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	handler.RespondError(w, r, http.StatusUnauthorized, err)
+}
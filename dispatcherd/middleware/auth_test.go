@@ -0,0 +1,165 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dispatcherd/auth"
+	"dispatcherd/handler"
+	"dispatcherd/middleware"
+	"dispatcherd/test"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthMiddleware(t *testing.T, scope auth.Scope) (http.Handler, **auth.Identity) {
+	t.Helper()
+
+	keys := []auth.APIKey{
+		{
+			Name:    "producer",
+			KeyHash: auth.HashKey("producer-key"),
+			Scopes:  []auth.Scope{auth.ScopeMessageSend},
+		},
+		{
+			Name:    "operator",
+			KeyHash: auth.HashKey("operator-key"),
+			Scopes:  []auth.Scope{auth.ScopeAdmin},
+		},
+	}
+
+	var captured *auth.Identity
+	authMw := middleware.NewAPIKeyAuthMiddleware(keys)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, _ = auth.IdentityFromContext(r.Context())
+	})
+
+	return authMw.OnRequest(middleware.RequireScope(scope)(next)), &captured
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	t.Run("accepts key from header", func(t *testing.T) {
+		h, captured := setupAuthMiddleware(t, auth.ScopeMessageSend)
+
+		req := httptest.NewRequest(http.MethodPost, "/message", nil)
+		req.Header.Set("X-API-Key", "producer-key")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "producer", (*captured).Name)
+	})
+
+	t.Run("accepts bearer token", func(t *testing.T) {
+		h, captured := setupAuthMiddleware(t, auth.ScopeMessageSend)
+
+		req := httptest.NewRequest(http.MethodPost, "/message", nil)
+		req.Header.Set("Authorization", "Bearer producer-key")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "producer", (*captured).Name)
+	})
+
+	t.Run("admin has every scope", func(t *testing.T) {
+		h, captured := setupAuthMiddleware(t, auth.ScopeRulesWrite)
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-API-Key", "operator-key")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "operator", (*captured).Name)
+	})
+
+	t.Run("rejects missing key", func(t *testing.T) {
+		h, captured := setupAuthMiddleware(t, auth.ScopeMessageSend)
+
+		req := httptest.NewRequest(http.MethodPost, "/message", nil)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+		assert.Nil(t, *captured)
+		test.AssertJSON(t, rr.Body.String(), handler.ErrorResponse{
+			APIVersion: 1,
+			Error: handler.ErrorResponseValue{
+				Code:    http.StatusUnauthorized,
+				Message: middleware.ErrMissingCredentials.Error(),
+				Errors:  []handler.ErrorResponseStack{},
+			},
+		})
+	})
+
+	t.Run("rejects invalid key", func(t *testing.T) {
+		h, captured := setupAuthMiddleware(t, auth.ScopeMessageSend)
+
+		req := httptest.NewRequest(http.MethodPost, "/message", nil)
+		req.Header.Set("X-API-Key", "unknown-key")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Nil(t, *captured)
+	})
+
+	t.Run("rejects missing scope", func(t *testing.T) {
+		h, captured := setupAuthMiddleware(t, auth.ScopeRulesRead)
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-API-Key", "producer-key")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Nil(t, *captured)
+	})
+}
+
+func TestAuthMiddlewareClientCertificate(t *testing.T) {
+	keys := []auth.APIKey{
+		{
+			Name:                  "producer",
+			CertificateCommonName: "producer.example.com",
+			Scopes:                []auth.Scope{auth.ScopeMessageSend},
+		},
+	}
+
+	var captured *auth.Identity
+	authMw := middleware.NewAPIKeyAuthMiddleware(keys)
+	h := authMw.OnRequest(middleware.RequireScope(auth.ScopeMessageSend)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = auth.IdentityFromContext(r.Context())
+		})))
+
+	newRequest := func(commonName string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/message", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+		}
+		return req
+	}
+
+	t.Run("maps known certificate to identity", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("producer.example.com"))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "producer", captured.Name)
+	})
+
+	t.Run("rejects unknown certificate", func(t *testing.T) {
+		captured = nil
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest("other.example.com"))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Nil(t, captured)
+	})
+}
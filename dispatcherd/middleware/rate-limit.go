@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"dispatcherd/auth"
+	"dispatcherd/handler"
+	"dispatcherd/ratelimit"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+type RateLimitKey string
+
+const (
+	RateLimitByAPIKey RateLimitKey = "apikey"
+	RateLimitByIP     RateLimitKey = "ip"
+	RateLimitByTag    RateLimitKey = "tag"
+)
+
+const globalBucket = "global"
+
+// bodies are read up to this size to find the tag to rate limit by, before the client is limited
+const maxPeekedBodySize = 1 << 20
+
+type RateLimitOptions struct {
+	// limit over all clients, unlimited if zero
+	Global ratelimit.Limit
+	// limit per client, can be overridden by the API key of the client
+	Client ratelimit.Limit
+	KeyBy  RateLimitKey
+	// tag to key clients by when using RateLimitByTag
+	KeyTag string
+	// proxies whose X-Forwarded-For header is trusted to determine the client IP
+	TrustedProxies []netip.Prefix
+}
+
+type RateLimitMiddleware struct {
+	opts    RateLimitOptions
+	global  *ratelimit.Limiter
+	clients *ratelimit.Limiter
+}
+
+func NewRateLimitMiddleware(opts RateLimitOptions) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		opts:    opts,
+		global:  ratelimit.NewLimiter(),
+		clients: ratelimit.NewLimiter(),
+	}
+}
+
+func (h *RateLimitMiddleware) OnRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientLimit := h.opts.Client
+		identity, authenticated := auth.IdentityFromContext(r.Context())
+		if authenticated && identity.RateLimit != nil {
+			clientLimit = *identity.RateLimit
+		}
+
+		clientKey, err := h.clientKey(w, r)
+		if err != nil {
+			handler.RespondError(w, r, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		allowed, retryAfter, refund := h.clients.Take(clientKey, clientLimit)
+		if !allowed {
+			respondTooManyRequests(w, r, ratelimit.RetryAfterSeconds(retryAfter))
+			return
+		}
+
+		if allowed, retryAfter := h.global.Allow(globalBucket, h.opts.Global); !allowed {
+			// the client is not charged for messages it could not send
+			refund()
+			respondTooManyRequests(w, r, ratelimit.RetryAfterSeconds(retryAfter))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies the client to rate limit. It only fails if the body to find the tag in is too large.
+func (h *RateLimitMiddleware) clientKey(w http.ResponseWriter, r *http.Request) (string, error) {
+	switch h.opts.KeyBy {
+	case RateLimitByAPIKey:
+		if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+			return "key:" + identity.Name, nil
+		}
+	case RateLimitByTag:
+		value, ok, err := peekTag(w, r, h.opts.KeyTag)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return "tag:" + value, nil
+		}
+	case RateLimitByIP:
+	}
+
+	// fall back to the client IP, if the preferred key is not available
+	return "ip:" + ClientIP(r, h.opts.TrustedProxies), nil
+}
+
+// peekTag reads a tag from a message body, without consuming the body for subsequent handlers. Bodies larger than
+// maxPeekedBodySize are rejected with an http.MaxBytesError.
+func peekTag(w http.ResponseWriter, r *http.Request, tagName string) (string, bool, error) {
+	if r.Body == nil {
+		return "", false, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPeekedBodySize))
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return "", false, err
+		}
+		return "", false, nil
+	}
+
+	var message struct {
+		Tags map[string]string `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		return "", false, nil
+	}
+
+	value, ok := message.Tags[tagName]
+	return value, ok, nil
+}
+
+// ClientIP determines the IP of the client. X-Forwarded-For is only considered if the request comes from a trusted
+// proxy, in which case the right-most address not belonging to a trusted proxy is the client.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+
+	if !isTrustedProxy(remoteIP, trustedProxies) {
+		return remoteIP
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(hop, trustedProxies) {
+			return hop
+		}
+		remoteIP = hop
+	}
+
+	// every hop is a trusted proxy, use the left-most one
+	return remoteIP
+}
+
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func respondTooManyRequests(w http.ResponseWriter, r *http.Request, retryAfterSeconds int) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	handler.RespondError(w, r, http.StatusTooManyRequests, ErrRateLimitExceeded)
+}
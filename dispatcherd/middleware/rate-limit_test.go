@@ -0,0 +1,191 @@
+package middleware_test
+
+import (
+	"dispatcherd/auth"
+	"dispatcherd/middleware"
+	"dispatcherd/ratelimit"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sendRequest(t *testing.T, h http.Handler, remoteAddr string, configure func(r *http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/message", nil)
+	req.RemoteAddr = remoteAddr
+	if configure != nil {
+		configure(req)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	rateLimit := middleware.NewRateLimitMiddleware(middleware.RateLimitOptions{
+		Client: ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Minute}},
+		KeyBy:  middleware.RateLimitByIP,
+	})
+	h := rateLimit.OnRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", nil).Code)
+
+	rr := sendRequest(t, h, "10.0.0.1:1234", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), middleware.ErrRateLimitExceeded.Error())
+
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.2:1234", nil).Code)
+}
+
+func TestRateLimitGlobal(t *testing.T) {
+	rateLimit := middleware.NewRateLimitMiddleware(middleware.RateLimitOptions{
+		Global: ratelimit.Limit{Rate: ratelimit.Rate{Events: 2, Per: time.Minute}},
+		KeyBy:  middleware.RateLimitByIP,
+	})
+	h := rateLimit.OnRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.2:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendRequest(t, h, "10.0.0.3:1234", nil).Code)
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	rateLimit := middleware.NewRateLimitMiddleware(middleware.RateLimitOptions{
+		Client: ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Minute}},
+		KeyBy:  middleware.RateLimitByAPIKey,
+	})
+	h := rateLimit.OnRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	withIdentity := func(identity *auth.Identity) func(r *http.Request) {
+		return func(r *http.Request) {
+			*r = *r.WithContext(auth.WithIdentity(r.Context(), identity))
+		}
+	}
+
+	t.Run("limits per key independent of IP", func(t *testing.T) {
+		identity := withIdentity(&auth.Identity{Name: "producer"})
+		assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", identity).Code)
+		assert.Equal(t, http.StatusTooManyRequests, sendRequest(t, h, "10.0.0.2:1234", identity).Code)
+	})
+
+	t.Run("uses limit of API key", func(t *testing.T) {
+		identity := withIdentity(&auth.Identity{
+			Name:      "bulk-producer",
+			RateLimit: &ratelimit.Limit{Rate: ratelimit.Rate{Events: 3, Per: time.Minute}},
+		})
+		for range 3 {
+			assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", identity).Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, sendRequest(t, h, "10.0.0.1:1234", identity).Code)
+	})
+}
+
+func TestRateLimitByIPWithClientLimits(t *testing.T) {
+	rateLimit := middleware.NewRateLimitMiddleware(middleware.RateLimitOptions{
+		Client: ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Minute}},
+		KeyBy:  middleware.RateLimitByIP,
+	})
+	h := rateLimit.OnRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	withLimit := func(name string, events int) func(r *http.Request) {
+		return func(r *http.Request) {
+			*r = *r.WithContext(auth.WithIdentity(r.Context(), &auth.Identity{
+				Name:      name,
+				RateLimit: &ratelimit.Limit{Rate: ratelimit.Rate{Events: events, Per: time.Minute}},
+			}))
+		}
+	}
+
+	// clients behind one IP alternate, the differing limits must not reset their buckets
+	first, second := withLimit("first", 2), withLimit("second", 3)
+	for range 2 {
+		assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", first).Code)
+		assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", second).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, sendRequest(t, h, "10.0.0.1:1234", first).Code)
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", second).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendRequest(t, h, "10.0.0.1:1234", second).Code)
+}
+
+func TestRateLimitByTag(t *testing.T) {
+	rateLimit := middleware.NewRateLimitMiddleware(middleware.RateLimitOptions{
+		Client: ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Minute}},
+		KeyBy:  middleware.RateLimitByTag,
+		KeyTag: "host",
+	})
+
+	var receivedBody string
+	h := rateLimit.OnRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+	}))
+
+	withHost := func(host string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Body = io.NopCloser(strings.NewReader(`{"title":"t","message":"m","tags":{"host":"` + host + `"}}`))
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", withHost("a")).Code)
+	// the body is still available to the handler
+	assert.Contains(t, receivedBody, `"host":"a"`)
+	assert.Equal(t, http.StatusTooManyRequests, sendRequest(t, h, "10.0.0.2:1234", withHost("a")).Code)
+	assert.Equal(t, http.StatusOK, sendRequest(t, h, "10.0.0.1:1234", withHost("b")).Code)
+}
+
+func TestRateLimitByTagBodyTooLarge(t *testing.T) {
+	rateLimit := middleware.NewRateLimitMiddleware(middleware.RateLimitOptions{
+		Client: ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Minute}},
+		KeyBy:  middleware.RateLimitByTag,
+		KeyTag: "host",
+	})
+	h := rateLimit.OnRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := sendRequest(t, h, "10.0.0.1:1234", func(r *http.Request) {
+		r.Body = io.NopCloser(strings.NewReader(`{"title":"` + strings.Repeat("x", 2<<20) + `"}`))
+	})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	newRequest := func(remoteAddr string, forwardedFor string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		return req
+	}
+
+	t.Run("ignores header of untrusted client", func(t *testing.T) {
+		req := newRequest("192.168.1.1:1234", "1.2.3.4")
+		assert.Equal(t, "192.168.1.1", middleware.ClientIP(req, trusted))
+	})
+
+	t.Run("uses header of trusted proxy", func(t *testing.T) {
+		req := newRequest("10.0.0.1:1234", "1.2.3.4")
+		assert.Equal(t, "1.2.3.4", middleware.ClientIP(req, trusted))
+	})
+
+	t.Run("skips trusted proxies in chain", func(t *testing.T) {
+		// the left-most entry is set by the client and must not be trusted
+		req := newRequest("10.0.0.1:1234", "6.6.6.6, 1.2.3.4, 10.0.0.2")
+		assert.Equal(t, "1.2.3.4", middleware.ClientIP(req, trusted))
+	})
+
+	t.Run("no proxies trusted", func(t *testing.T) {
+		req := newRequest("10.0.0.1:1234", "1.2.3.4")
+		assert.Equal(t, "10.0.0.1", middleware.ClientIP(req, nil))
+	})
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// remove idle buckets every cleanupInterval calls, so that the limiter does not grow unbounded with the number of keys
+const cleanupInterval = 1000
+
+// Limiter is a set of token buckets, one per key.
+type Limiter struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	buckets map[string]*rate.Limiter
+	calls   int
+}
+
+func NewLimiter() *Limiter {
+	return NewLimiterWithClock(time.Now)
+}
+
+func NewLimiterWithClock(now func() time.Time) *Limiter {
+	return &Limiter{
+		now:     now,
+		buckets: make(map[string]*rate.Limiter),
+	}
+}
+
+// Allow takes a token from the bucket of the given key. If the bucket is empty, it returns false along with the
+// duration until the next token is available.
+func (l *Limiter) Allow(key string, limit Limit) (bool, time.Duration) {
+	allowed, delay, _ := l.Take(key, limit)
+	return allowed, delay
+}
+
+// Take works like Allow, and additionally returns a function which puts the token back, for events which are rejected
+// after all.
+func (l *Limiter) Take(key string, limit Limit) (bool, time.Duration, func()) {
+	if limit.Rate.IsZero() {
+		return true, 0, func() {}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	bucket := l.bucket(key, limit)
+	reservation := bucket.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		// do not consume a token for rejected events
+		reservation.CancelAt(now)
+		return false, delay, func() {}
+	}
+
+	refund := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		reservation.CancelAt(l.now())
+	}
+
+	return true, 0, refund
+}
+
+// Reserve takes a token from the bucket of the given key, even if it is empty, and returns how long the caller has to
+// wait before the event may happen.
+func (l *Limiter) Reserve(key string, limit Limit) time.Duration {
+	if limit.Rate.IsZero() {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	return l.bucket(key, limit).ReserveN(now, 1).DelayFrom(now)
+}
+
+// bucket returns the bucket of the key for the limit. Events of one key under different limits, e.g. clients with
+// their own limits sharing an IP, are counted apart, instead of resetting a shared bucket whenever the limit changes.
+func (l *Limiter) bucket(key string, limit Limit) *rate.Limiter {
+	key += "|" + limit.Rate.String() + "|" + strconv.Itoa(limit.burst())
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = rate.NewLimiter(limit.Rate.Limit(), limit.burst())
+		l.buckets[key] = bucket
+	}
+
+	return bucket
+}
+
+func (l *Limiter) cleanup(now time.Time) {
+	l.calls++
+	if l.calls < cleanupInterval {
+		return
+	}
+	l.calls = 0
+
+	for key, bucket := range l.buckets {
+		// a full bucket behaves exactly like a new one
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RetryAfterSeconds rounds a delay up to full seconds, as used by the Retry-After header.
+func RetryAfterSeconds(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
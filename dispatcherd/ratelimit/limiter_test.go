@@ -0,0 +1,99 @@
+package ratelimit_test
+
+import (
+	"dispatcherd/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := map[string]ratelimit.Rate{
+		"30/min": {Events: 30, Per: time.Minute},
+		"10/s":   {Events: 10, Per: time.Second},
+		"5/10s":  {Events: 5, Per: 10 * time.Second},
+		"100/h":  {Events: 100, Per: time.Hour},
+	}
+
+	for value, expected := range tests {
+		t.Run(value, func(t *testing.T) {
+			actual, err := ratelimit.ParseRate(value)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	for _, value := range []string{"", "30", "x/min", "0/min", "30/fortnight", "30/-1s"} {
+		t.Run("invalid "+value, func(t *testing.T) {
+			_, err := ratelimit.ParseRate(value)
+			assert.ErrorIs(t, err, ratelimit.ErrInvalidRate)
+		})
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.NewLimiterWithClock(func() time.Time { return now })
+	limit := ratelimit.Limit{Rate: ratelimit.Rate{Events: 2, Per: time.Minute}}
+
+	// burst defaults to the number of events
+	allowed, _ := limiter.Allow("a", limit)
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a", limit)
+	assert.True(t, allowed)
+
+	allowed, retryAfter := limiter.Allow("a", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// other keys have their own bucket
+	allowed, _ = limiter.Allow("b", limit)
+	assert.True(t, allowed)
+
+	// rejected events do not consume tokens
+	now = now.Add(30 * time.Second)
+	allowed, _ = limiter.Allow("a", limit)
+	assert.True(t, allowed)
+}
+
+func TestLimiterTakeRefund(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.NewLimiterWithClock(func() time.Time { return now })
+	limit := ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Minute}}
+
+	allowed, _, refund := limiter.Take("a", limit)
+	require.True(t, allowed)
+	refund()
+
+	// the token is available again
+	allowed, _ = limiter.Allow("a", limit)
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a", limit)
+	assert.False(t, allowed)
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := ratelimit.NewLimiter()
+	for range 100 {
+		allowed, _ := limiter.Allow("a", ratelimit.Limit{})
+		assert.True(t, allowed)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, ratelimit.RetryAfterSeconds(100*time.Millisecond))
+	assert.Equal(t, 30, ratelimit.RetryAfterSeconds(30*time.Second))
+}
+
+func TestLimiterReserve(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.NewLimiterWithClock(func() time.Time { return now })
+	limit := ratelimit.Limit{Rate: ratelimit.Rate{Events: 30, Per: time.Minute}, Burst: 1}
+
+	assert.Equal(t, time.Duration(0), limiter.Reserve("a", limit))
+	// every reservation is queued behind the previous one
+	assert.Equal(t, 2*time.Second, limiter.Reserve("a", limit))
+	assert.Equal(t, 4*time.Second, limiter.Reserve("a", limit))
+}
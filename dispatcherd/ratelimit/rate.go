@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var ErrInvalidRate = errors.New("invalid rate, expected format <events>/<unit>, e.g. 30/min")
+
+var rateUnits = map[string]time.Duration{
+	"s":      time.Second,
+	"sec":    time.Second,
+	"second": time.Second,
+	"m":      time.Minute,
+	"min":    time.Minute,
+	"minute": time.Minute,
+	"h":      time.Hour,
+	"hour":   time.Hour,
+	"d":      24 * time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// Rate is a number of events per interval, written as e.g. "30/min" or "5/10s" in configs.
+type Rate struct {
+	Events int
+	Per    time.Duration
+}
+
+func ParseRate(value string) (Rate, error) {
+	events, unit, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return Rate{}, fmt.Errorf("%w: %s", ErrInvalidRate, value)
+	}
+
+	count, err := strconv.Atoi(events)
+	if err != nil || count <= 0 {
+		return Rate{}, fmt.Errorf("%w: %s", ErrInvalidRate, value)
+	}
+
+	per, ok := rateUnits[unit]
+	if !ok {
+		per, err = time.ParseDuration(unit)
+		if err != nil || per <= 0 {
+			return Rate{}, fmt.Errorf("%w: %s", ErrInvalidRate, value)
+		}
+	}
+
+	return Rate{Events: count, Per: per}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.Events == 0 || r.Per == 0
+}
+
+func (r Rate) Limit() rate.Limit {
+	if r.IsZero() {
+		return rate.Inf
+	}
+
+	return rate.Every(r.Per / time.Duration(r.Events))
+}
+
+func (r Rate) String() string {
+	return strconv.Itoa(r.Events) + "/" + r.Per.String()
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*r = Rate{}
+		return nil
+	}
+
+	parsed, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+
+	return nil
+}
+
+func (r Rate) MarshalText() ([]byte, error) {
+	if r.IsZero() {
+		return []byte{}, nil
+	}
+
+	return []byte(r.String()), nil
+}
+
+type Limit struct {
+	Rate Rate `json:"rate"`
+	// number of events allowed at once, defaults to the events of the rate
+	Burst int `json:"burst" validate:"min=0"`
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+
+	return max(l.Rate.Events, 1)
+}
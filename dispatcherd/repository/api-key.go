@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/auth"
+	"dispatcherd/logging"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type APIKeyRepository interface {
+	ListAPIKeys(ctx context.Context) ([]auth.APIKey, error)
+}
+
+type FilesystemAPIKeyRepository struct {
+	logger       *slog.Logger
+	validate     *validator.Validate
+	keyDirectory string
+}
+
+func NewFilesystemAPIKeyRepository(keyDirectory string) *FilesystemAPIKeyRepository {
+	return &FilesystemAPIKeyRepository{
+		logger:       logging.GetLogger(logging.DataAccess),
+		validate:     validator.New(validator.WithRequiredStructEnabled()),
+		keyDirectory: keyDirectory,
+	}
+}
+
+func (r *FilesystemAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]auth.APIKey, error) {
+	var keys []auth.APIKey
+
+	r.logger.DebugContext(ctx, "loading api keys from "+r.keyDirectory)
+
+	files, err := os.ReadDir(r.keyDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			filePath := filepath.Join(r.keyDirectory, file.Name())
+			fileContent, err := os.ReadFile(filePath)
+			if err != nil {
+				r.logger.ErrorContext(ctx, "failed to read api key file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+
+			var key auth.APIKey
+			if err := json.Unmarshal(fileContent, &key); err != nil {
+				r.logger.ErrorContext(ctx, "failed to unmarshal api key file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+
+			if err := r.validate.Struct(key); err != nil {
+				r.logger.ErrorContext(ctx, "invalid api key file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	r.logger.InfoContext(ctx, "loaded "+strconv.Itoa(len(keys))+" api keys")
+
+	return keys, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/auth"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemAPIKeyRepositoryListAPIKeys(t *testing.T) {
+	tempDir := t.TempDir()
+
+	keyJSON := `{"name":"producer","keyHash":"` + auth.HashKey("secret") + `","scopes":["message:send"]}`
+	certificateJSON := `{"name":"collector","certificateCommonName":"collector.example.com","scopes":["message:send"]}`
+	invalidHashJSON := `{"name":"invalid","keyHash":"secret","scopes":["message:send"]}`
+	invalidScopeJSON := `{"name":"invalid","keyHash":"` + auth.HashKey("other") + `","scopes":["unknown"]}`
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "producer.json"), []byte(keyJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "collector.json"), []byte(certificateJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "invalid-hash.json"), []byte(invalidHashJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "invalid-scope.json"), []byte(invalidScopeJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "ignore.txt"), []byte("ignore me"), 0644))
+
+	repo := NewFilesystemAPIKeyRepository(tempDir)
+
+	keys, err := repo.ListAPIKeys(context.Background())
+	assert.NoError(t, err)
+
+	expectedKeys := []auth.APIKey{
+		{
+			Name:                  "collector",
+			CertificateCommonName: "collector.example.com",
+			Scopes:                []auth.Scope{auth.ScopeMessageSend},
+		},
+		{
+			Name:    "producer",
+			KeyHash: auth.HashKey("secret"),
+			Scopes:  []auth.Scope{auth.ScopeMessageSend},
+		},
+	}
+	assert.Equal(t, expectedKeys, keys)
+}
+
+func TestFilesystemAPIKeyRepositoryNonExistentDirectory(t *testing.T) {
+	repo := NewFilesystemAPIKeyRepository("/non-existent-dir")
+
+	_, err := repo.ListAPIKeys(context.Background())
+	assert.Error(t, err)
+}
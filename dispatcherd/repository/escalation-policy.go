@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type EscalationPolicyRepository interface {
+	ListEscalationPolicies(ctx context.Context) ([]dispatch.EscalationPolicy, error)
+}
+
+type FilesystemEscalationPolicyRepository struct {
+	logger          *slog.Logger
+	validate        *validator.Validate
+	policyDirectory string
+}
+
+func NewFilesystemEscalationPolicyRepository(policyDirectory string) *FilesystemEscalationPolicyRepository {
+	return &FilesystemEscalationPolicyRepository{
+		logger:          logging.GetLogger(logging.DataAccess),
+		validate:        validator.New(validator.WithRequiredStructEnabled()),
+		policyDirectory: policyDirectory,
+	}
+}
+
+func (r *FilesystemEscalationPolicyRepository) ListEscalationPolicies(ctx context.Context) ([]dispatch.EscalationPolicy, error) {
+	var policies []dispatch.EscalationPolicy
+
+	r.logger.DebugContext(ctx, "loading escalation policies from "+r.policyDirectory)
+
+	files, err := os.ReadDir(r.policyDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			filePath := filepath.Join(r.policyDirectory, file.Name())
+			fileContent, err := os.ReadFile(filePath)
+			if err != nil {
+				r.logger.ErrorContext(ctx, "failed to read escalation policy file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+
+			var policy dispatch.EscalationPolicy
+			if err := json.Unmarshal(fileContent, &policy); err != nil {
+				r.logger.ErrorContext(ctx, "failed to unmarshal escalation policy file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+
+			if err := r.validate.Struct(policy); err != nil {
+				r.logger.ErrorContext(ctx, "invalid escalation policy file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+			policies = append(policies, policy)
+		}
+	}
+
+	r.logger.InfoContext(ctx, "loaded "+strconv.Itoa(len(policies))+" escalation policies")
+
+	return policies, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemEscalationPolicyRepositoryListEscalationPolicies(t *testing.T) {
+	tempDir := t.TempDir()
+
+	policyJSON := `{"name":"on-call","steps":[{"dispatcherName":"primary","timeout":"5m"},{"dispatcherName":"secondary"}]}`
+	noStepsJSON := `{"name":"invalid","steps":[]}`
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "on-call.json"), []byte(policyJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "no-steps.json"), []byte(noStepsJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "ignore.txt"), []byte("ignore me"), 0644))
+
+	repo := NewFilesystemEscalationPolicyRepository(tempDir)
+
+	policies, err := repo.ListEscalationPolicies(context.Background())
+	assert.NoError(t, err)
+
+	expectedPolicies := []dispatch.EscalationPolicy{
+		{
+			Name: "on-call",
+			Steps: []dispatch.EscalationStep{
+				{DispatcherName: "primary", Timeout: dispatch.Duration(5 * time.Minute)},
+				{DispatcherName: "secondary"},
+			},
+		},
+	}
+	assert.Equal(t, expectedPolicies, policies)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type EscalationRepository interface {
+	ListEscalations(ctx context.Context) ([]dispatch.Escalation, error)
+	SaveEscalation(ctx context.Context, escalation dispatch.Escalation) error
+	DeleteEscalation(ctx context.Context, messageID string, policy string) error
+}
+
+// FilesystemEscalationRepository stores each pending escalation as a JSON file named after the message ID and the
+// policy, as a message can escalate through several policies at once.
+type FilesystemEscalationRepository struct {
+	logger         *slog.Logger
+	stateDirectory string
+}
+
+func NewFilesystemEscalationRepository(stateDirectory string) *FilesystemEscalationRepository {
+	return &FilesystemEscalationRepository{
+		logger:         logging.GetLogger(logging.DataAccess),
+		stateDirectory: stateDirectory,
+	}
+}
+
+func (r *FilesystemEscalationRepository) ListEscalations(ctx context.Context) ([]dispatch.Escalation, error) {
+	var escalations []dispatch.Escalation
+
+	files, err := os.ReadDir(r.stateDirectory)
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was persisted yet
+		return escalations, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			filePath := filepath.Join(r.stateDirectory, file.Name())
+			fileContent, err := os.ReadFile(filePath)
+			if err != nil {
+				r.logger.ErrorContext(ctx, "failed to read escalation file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+
+			var escalation dispatch.Escalation
+			if err := json.Unmarshal(fileContent, &escalation); err != nil || escalation.Message == nil {
+				r.logger.ErrorContext(ctx, "failed to unmarshal escalation file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+			escalations = append(escalations, escalation)
+		}
+	}
+
+	r.logger.InfoContext(ctx, "loaded "+strconv.Itoa(len(escalations))+" pending escalations")
+
+	return escalations, nil
+}
+
+func (r *FilesystemEscalationRepository) SaveEscalation(ctx context.Context, escalation dispatch.Escalation) error {
+	content, err := json.Marshal(escalation)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.stateDirectory, 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a crash never leaves a partial file behind
+	filePath := r.filePath(escalation.Message.ID, escalation.Policy)
+	tempPath := filePath + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, filePath)
+}
+
+func (r *FilesystemEscalationRepository) DeleteEscalation(ctx context.Context, messageID string, policy string) error {
+	err := os.Remove(r.filePath(messageID, policy))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *FilesystemEscalationRepository) filePath(messageID string, policy string) string {
+	return filepath.Join(r.stateDirectory, filepath.Base(messageID)+"_"+url.PathEscape(policy)+".json")
+}
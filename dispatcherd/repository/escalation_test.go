@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemEscalationRepository(t *testing.T) {
+	repo := NewFilesystemEscalationRepository(filepath.Join(t.TempDir(), "escalations"))
+
+	// the directory is created on the first save
+	escalations, err := repo.ListEscalations(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, escalations)
+
+	escalation := dispatch.Escalation{
+		Policy:  "on-call",
+		Message: dispatch.NewMessage("title", "message", map[string]string{"tag": "value"}),
+		Steps: []dispatch.EscalationStep{
+			{DispatcherName: "primary", Timeout: dispatch.Duration(time.Minute)},
+			{DispatcherName: "secondary"},
+		},
+		NextAt: time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, repo.SaveEscalation(context.Background(), escalation))
+
+	// the same message escalating through another policy is kept separately
+	other := escalation
+	other.Policy = "management/escalation"
+	require.NoError(t, repo.SaveEscalation(context.Background(), other))
+
+	escalations, err = repo.ListEscalations(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []dispatch.Escalation{escalation, other}, escalations)
+
+	require.NoError(t, repo.DeleteEscalation(context.Background(), escalation.Message.ID, escalation.Policy))
+	escalations, err = repo.ListEscalations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []dispatch.Escalation{other}, escalations)
+
+	// deleting twice is fine
+	assert.NoError(t, repo.DeleteEscalation(context.Background(), escalation.Message.ID, escalation.Policy))
+}
@@ -51,6 +51,11 @@ func (r *FilesystemRuleRepository) ListRules(ctx context.Context) ([]dispatch.Ru
 				r.logger.ErrorContext(ctx, "failed to unmarshal rule file", logging.FieldError, err, "file", filePath)
 				continue
 			}
+
+			if err := rule.Compile(); err != nil {
+				r.logger.ErrorContext(ctx, "invalid rule expression", logging.FieldError, err, "file", filePath)
+				continue
+			}
 			rules = append(rules, rule)
 		}
 	}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFilesystemRuleRepositoryListRules(t *testing.T) {
@@ -89,6 +90,29 @@ func TestFilesystemRuleRepositoryListRulesFailed(t *testing.T) {
 		assert.Len(t, rules, 0)
 	})
 
+	t.Run("invalid expression", func(t *testing.T) {
+		tempDir, err := os.MkdirTemp("", "rules")
+		assert.NoError(t, err)
+		defer func(path string) {
+			_ = os.RemoveAll(path)
+		}(tempDir)
+
+		invalidRule := `{"id":"rule1","dispatcherName":"dispatcher1","expression":"tags.severity =="}`
+		err = os.WriteFile(filepath.Join(tempDir, "rule1.json"), []byte(invalidRule), 0644)
+		assert.NoError(t, err)
+
+		validRule := `{"id":"rule2","dispatcherName":"dispatcher2","expression":"'severity' in tags"}`
+		err = os.WriteFile(filepath.Join(tempDir, "rule2.json"), []byte(validRule), 0644)
+		assert.NoError(t, err)
+
+		repo := NewFilesystemRuleRepository(tempDir)
+
+		rules, err := repo.ListRules(context.Background())
+		assert.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, "rule2", rules[0].ID)
+	})
+
 	t.Run("unreadable file", func(t *testing.T) {
 		tempDir, err := os.MkdirTemp("", "rules")
 		assert.NoError(t, err)
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type SilenceRepository interface {
+	ListSilences(ctx context.Context) ([]dispatch.Silence, error)
+	SaveSilence(ctx context.Context, silence dispatch.Silence) error
+	DeleteSilence(ctx context.Context, id string) error
+}
+
+// FilesystemSilenceRepository stores each silence as a JSON file named after its ID.
+type FilesystemSilenceRepository struct {
+	logger         *slog.Logger
+	stateDirectory string
+}
+
+func NewFilesystemSilenceRepository(stateDirectory string) *FilesystemSilenceRepository {
+	return &FilesystemSilenceRepository{
+		logger:         logging.GetLogger(logging.DataAccess),
+		stateDirectory: stateDirectory,
+	}
+}
+
+func (r *FilesystemSilenceRepository) ListSilences(ctx context.Context) ([]dispatch.Silence, error) {
+	var silences []dispatch.Silence
+
+	files, err := os.ReadDir(r.stateDirectory)
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was persisted yet
+		return silences, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			filePath := filepath.Join(r.stateDirectory, file.Name())
+			fileContent, err := os.ReadFile(filePath)
+			if err != nil {
+				r.logger.ErrorContext(ctx, "failed to read silence file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+
+			var silence dispatch.Silence
+			if err := json.Unmarshal(fileContent, &silence); err != nil || silence.ID == "" {
+				r.logger.ErrorContext(ctx, "failed to unmarshal silence file", logging.FieldError, err, "file", filePath)
+				continue
+			}
+			silences = append(silences, silence)
+		}
+	}
+
+	r.logger.InfoContext(ctx, "loaded "+strconv.Itoa(len(silences))+" silences")
+
+	return silences, nil
+}
+
+func (r *FilesystemSilenceRepository) SaveSilence(ctx context.Context, silence dispatch.Silence) error {
+	content, err := json.Marshal(silence)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.stateDirectory, 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a crash never leaves a partial file behind
+	filePath := r.filePath(silence.ID)
+	tempPath := filePath + ".tmp"
+	if err := os.WriteFile(tempPath, content, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, filePath)
+}
+
+func (r *FilesystemSilenceRepository) DeleteSilence(ctx context.Context, id string) error {
+	err := os.Remove(r.filePath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *FilesystemSilenceRepository) filePath(id string) string {
+	return filepath.Join(r.stateDirectory, filepath.Base(id)+".json")
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemSilenceRepository(t *testing.T) {
+	repo := NewFilesystemSilenceRepository(filepath.Join(t.TempDir(), "silences"))
+
+	// the directory is created on the first save
+	silences, err := repo.ListSilences(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, silences)
+
+	silence := dispatch.Silence{
+		ID:        "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-1"}},
+		StartsAt:  time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC),
+		EndsAt:    time.Date(2025, 11, 1, 14, 0, 0, 0, time.UTC),
+		CreatedBy: "operator",
+		Owner:     "team-a",
+		CreatedAt: time.Date(2025, 11, 1, 11, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, repo.SaveSilence(context.Background(), silence))
+
+	silences, err = repo.ListSilences(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []dispatch.Silence{silence}, silences)
+
+	require.NoError(t, repo.DeleteSilence(context.Background(), silence.ID))
+	silences, err = repo.ListSilences(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, silences)
+
+	// deleting twice is fine
+	assert.NoError(t, repo.DeleteSilence(context.Background(), silence.ID))
+}
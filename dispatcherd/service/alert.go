@@ -0,0 +1,108 @@
+package service
+
+import (
+	"dispatcherd/dispatch"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// openAlert is an open alert, along with the transformation of the firing message per dispatcher, which is applied
+// to the resolve notification as well.
+type openAlert struct {
+	dispatch.Alert
+	transforms map[string]*dispatch.Transformation
+}
+
+// alertStore tracks the open alerts by their key.
+type alertStore struct {
+	mu     sync.Mutex
+	alerts map[string]*openAlert
+}
+
+func newAlertStore() *alertStore {
+	return &alertStore{
+		alerts: make(map[string]*openAlert),
+	}
+}
+
+// fire opens the alert of the message, or updates it if it is already open. The owner is recorded when the alert is
+// opened.
+func (s *alertStore) fire(message *dispatch.Message, owner string, deliveries []delivery) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, ok := s.alerts[message.Key]
+	if !ok {
+		alert = &openAlert{
+			Alert: dispatch.Alert{
+				Key:     message.Key,
+				Owner:   owner,
+				FiredAt: now,
+			},
+			transforms: make(map[string]*dispatch.Transformation),
+		}
+		s.alerts[message.Key] = alert
+	}
+
+	alert.Title = message.Title
+	alert.Message = message.Message
+	alert.Tags = maps.Clone(message.Tags)
+	alert.MessageID = message.ID
+	alert.UpdatedAt = now
+	for _, d := range deliveries {
+		if !slices.Contains(alert.Dispatchers, d.dispatcherName) {
+			alert.Dispatchers = append(alert.Dispatchers, d.dispatcherName)
+		}
+		alert.transforms[d.dispatcherName] = d.transform
+	}
+}
+
+// owner returns the client which fired the open alert with the given key.
+func (s *alertStore) owner(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, ok := s.alerts[key]
+	if !ok {
+		return "", false
+	}
+
+	return alert.Owner, true
+}
+
+// resolve closes the alert with the given key, returning it if it was open.
+func (s *alertStore) resolve(key string) (openAlert, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, ok := s.alerts[key]
+	if !ok {
+		return openAlert{}, false
+	}
+	delete(s.alerts, key)
+
+	return *alert, true
+}
+
+// list returns the open alerts, oldest first.
+func (s *alertStore) list() []dispatch.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := make([]dispatch.Alert, 0, len(s.alerts))
+	for _, alert := range s.alerts {
+		copied := alert.Alert
+		copied.Dispatchers = slices.Clone(alert.Dispatchers)
+		alerts = append(alerts, copied)
+	}
+
+	slices.SortFunc(alerts, func(a, b dispatch.Alert) int {
+		return a.FiredAt.Compare(b.FiredAt)
+	})
+
+	return alerts
+}
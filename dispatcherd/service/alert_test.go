@@ -0,0 +1,111 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/auth"
+	"dispatcherd/dispatch"
+	"dispatcherd/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAlertMessage(title string, key string, status dispatch.MessageStatus) *dispatch.Message {
+	message := dispatch.NewMessage(title, "message", map[string]string{"host": "a"})
+	message.Key = key
+	message.Status = status
+	return message
+}
+
+func TestAlertResolve(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "ops",
+		Type: "mock",
+	})
+
+	firing := newAlertMessage("disk full", "disk-a", dispatch.StatusFiring)
+	require.NoError(t, messageService.QueueMessage(context.Background(), firing))
+
+	alerts, err := messageService.ListAlerts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "disk-a", alerts[0].Key)
+	assert.Equal(t, firing.ID, alerts[0].MessageID)
+	assert.Equal(t, []string{"ops"}, alerts[0].Dispatchers)
+
+	resolved := newAlertMessage("disk no longer full", "disk-a", dispatch.StatusResolved)
+	require.NoError(t, messageService.QueueMessage(context.Background(), resolved))
+
+	assert.Equal(t, []*dispatch.Message{firing, resolved}, dispatcher.Messages())
+	alerts, err = messageService.ListAlerts(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	// resolving an alert which is not open notifies nobody
+	require.NoError(t, messageService.QueueMessage(context.Background(), resolved))
+	assert.Len(t, dispatcher.Messages(), 2)
+}
+
+func TestAlertOwner(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "ops",
+		Type: "mock",
+	})
+
+	teamA := auth.WithIdentity(context.Background(), &auth.Identity{Name: "team-a"})
+	teamB := auth.WithIdentity(context.Background(), &auth.Identity{Name: "team-b"})
+	admin := auth.WithIdentity(context.Background(), &auth.Identity{Name: "ops", Scopes: []auth.Scope{auth.ScopeAdmin}})
+
+	err := messageService.QueueMessage(teamA, newAlertMessage("disk full", "disk-a", dispatch.StatusFiring))
+	require.NoError(t, err)
+	alerts, err := messageService.ListAlerts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "team-a", alerts[0].Owner)
+
+	// other clients can neither update nor resolve the alert
+	err = messageService.QueueMessage(teamB, newAlertMessage("disk full", "disk-a", dispatch.StatusFiring))
+	assert.ErrorIs(t, err, service.ErrOwnedByOtherClient)
+	err = messageService.QueueMessage(teamB, newAlertMessage("disk fine", "disk-a", dispatch.StatusResolved))
+	assert.ErrorIs(t, err, service.ErrOwnedByOtherClient)
+	assert.Len(t, dispatcher.Messages(), 1)
+
+	// admins may resolve every alert
+	err = messageService.QueueMessage(admin, newAlertMessage("disk fine", "disk-a", dispatch.StatusResolved))
+	require.NoError(t, err)
+	alerts, err = messageService.ListAlerts(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestAlertResolveTransformed(t *testing.T) {
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{
+				DispatcherName: "ops",
+				Transform:      &dispatch.Transformation{RemoveTags: []string{"host"}, TitlePrefix: "[PROD] "},
+			}}, nil
+		},
+	}
+
+	dispatcher := &recordingDispatcher{}
+	messageService := service.NewMessageService(mre, func(typeName string) (dispatch.Dispatcher, error) {
+		return dispatcher, nil
+	}, nil, nil)
+	require.NoError(t, messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{Name: "ops", Type: "mock"}))
+
+	require.NoError(t, messageService.QueueMessage(context.Background(),
+		newAlertMessage("disk full", "disk-a", dispatch.StatusFiring)))
+	require.NoError(t, messageService.QueueMessage(context.Background(),
+		newAlertMessage("disk no longer full", "disk-a", dispatch.StatusResolved)))
+
+	// the resolve notification is shaped like the firing message
+	messages := dispatcher.Messages()
+	require.Len(t, messages, 2)
+	for _, message := range messages {
+		assert.NotContains(t, message.Tags, "host")
+	}
+	assert.Equal(t, "[PROD] disk no longer full", messages[1].Title)
+	assert.Equal(t, dispatch.StatusResolved, messages[1].Status)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+)
+
+type dedupEntry struct {
+	ctx      context.Context
+	message  *dispatch.Message
+	repeated int
+	timer    *time.Timer
+}
+
+// deduplicator suppresses repeated messages within a window. Once the window of a message closes, the number of
+// suppressed repetitions is reported.
+type deduplicator struct {
+	logger *slog.Logger
+	config *dispatch.DedupConfig
+	report func(ctx context.Context, message *dispatch.Message)
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+func newDeduplicator(config *dispatch.DedupConfig, report func(ctx context.Context, message *dispatch.Message)) *deduplicator {
+	return &deduplicator{
+		logger:  logging.GetLogger(logging.MessageProcessing),
+		config:  config,
+		report:  report,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// admit returns whether the message is delivered, or suppressed as a repetition.
+func (d *deduplicator) admit(ctx context.Context, message *dispatch.Message) bool {
+	fingerprint := message.Fingerprint(d.config.Tags)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if entry, ok := d.entries[fingerprint]; ok {
+		entry.repeated++
+		d.logger.DebugContext(ctx, fmt.Sprintf("suppressed repeated message, first delivered as %s", entry.message.ID))
+		return false
+	}
+
+	entry := &dedupEntry{
+		ctx:     context.WithoutCancel(ctx),
+		message: message,
+	}
+	entry.timer = time.AfterFunc(d.config.Window.Duration(), func() {
+		d.closeWindow(fingerprint, entry)
+	})
+	d.entries[fingerprint] = entry
+
+	return true
+}
+
+func (d *deduplicator) closeWindow(fingerprint string, entry *dedupEntry) {
+	d.mu.Lock()
+	if d.entries[fingerprint] != entry {
+		// already flushed
+		d.mu.Unlock()
+		return
+	}
+	delete(d.entries, fingerprint)
+	d.mu.Unlock()
+
+	d.reportRepetitions(entry)
+}
+
+// flush closes all windows immediately.
+func (d *deduplicator) flush() {
+	d.mu.Lock()
+	entries := d.entries
+	d.entries = make(map[string]*dedupEntry)
+	d.mu.Unlock()
+
+	for entry := range maps.Values(entries) {
+		entry.timer.Stop()
+		d.reportRepetitions(entry)
+	}
+}
+
+func (d *deduplicator) reportRepetitions(entry *dedupEntry) {
+	if entry.repeated == 0 {
+		return
+	}
+
+	message := dispatch.NewMessage(
+		fmt.Sprintf("%s (repeated %d times)", entry.message.Title, entry.repeated),
+		entry.message.Message,
+		maps.Clone(entry.message.Tags),
+	)
+	d.report(message.AnnotateContext(entry.ctx), message)
+}
@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupSuppressesRepetitions(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "deduplicated",
+		Type: "mock",
+		Dedup: &dispatch.DedupConfig{
+			Window: dispatch.Duration(100 * time.Millisecond),
+			Tags:   []string{"host"},
+		},
+	})
+
+	for _, host := range []string{"a", "a", "b", "a"} {
+		msg := dispatch.NewMessage("Disk full", "disk is full", map[string]string{"host": host})
+		require.NoError(t, messageService.QueueMessage(context.Background(), msg))
+	}
+
+	messages := dispatcher.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", messages[0].Tags["host"])
+	assert.Equal(t, "b", messages[1].Tags["host"])
+
+	// repetitions are reported once the window closes
+	assert.Eventually(t, func() bool { return len(dispatcher.Messages()) == 3 }, time.Second, 5*time.Millisecond)
+	report := dispatcher.Messages()[2]
+	assert.Equal(t, "Disk full (repeated 2 times)", report.Title)
+	assert.Equal(t, "a", report.Tags["host"])
+
+	// the window starts over after it closed
+	msg := dispatch.NewMessage("Disk full", "disk is full", map[string]string{"host": "a"})
+	require.NoError(t, messageService.QueueMessage(context.Background(), msg))
+	assert.Len(t, dispatcher.Messages(), 4)
+}
+
+func TestDedupKey(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "deduplicated",
+		Type: "mock",
+		Dedup: &dispatch.DedupConfig{
+			Window: dispatch.Duration(time.Hour),
+		},
+	})
+
+	for _, title := range []string{"Disk 91% full", "Disk 92% full"} {
+		msg := dispatch.NewMessage(title, "disk is full", nil)
+		msg.DedupKey = "disk-full"
+		require.NoError(t, messageService.QueueMessage(context.Background(), msg))
+	}
+	assert.Len(t, dispatcher.Messages(), 1)
+
+	// pending repetitions are reported on shutdown
+	require.NoError(t, messageService.Shutdown(context.Background()))
+	messages := dispatcher.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Disk 91% full (repeated 1 times)", messages[1].Title)
+}
+
+func TestDedupRequiresWindow(t *testing.T) {
+	messageService := service.NewMessageService(&MockRuleEngine{}, func(typeName string) (dispatch.Dispatcher, error) {
+		return &recordingDispatcher{}, nil
+	}, nil, nil)
+
+	err := messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{
+		Name:  "deduplicated",
+		Type:  "mock",
+		Dedup: &dispatch.DedupConfig{Window: dispatch.Duration(-time.Minute)},
+	})
+	assert.ErrorIs(t, err, service.ErrDispatcherConfigInvalid)
+}
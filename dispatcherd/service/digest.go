@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// digest buffers messages and combines them into a single message, sent once the interval elapsed or the buffer is
+// full.
+type digest struct {
+	logger *slog.Logger
+	config *dispatch.DigestConfig
+	send   func(ctx context.Context, message *dispatch.Message)
+
+	mu       sync.Mutex
+	messages []*dispatch.Message
+	timer    *time.Timer
+}
+
+func newDigest(config *dispatch.DigestConfig, send func(ctx context.Context, message *dispatch.Message)) *digest {
+	return &digest{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		config: config,
+		send:   send,
+	}
+}
+
+func (d *digest) add(ctx context.Context, message *dispatch.Message) {
+	d.mu.Lock()
+	d.messages = append(d.messages, message)
+	d.logger.DebugContext(ctx, fmt.Sprintf("added message to digest, %d messages buffered", len(d.messages)))
+
+	if len(d.messages) == 1 {
+		d.timer = time.AfterFunc(d.config.Interval.Duration(), d.flush)
+	}
+
+	if d.config.MaxSize > 0 && len(d.messages) >= d.config.MaxSize {
+		messages := d.take()
+		d.mu.Unlock()
+		d.emit(messages)
+		return
+	}
+	d.mu.Unlock()
+}
+
+// flush sends the buffered messages right away.
+func (d *digest) flush() {
+	d.mu.Lock()
+	messages := d.take()
+	d.mu.Unlock()
+
+	d.emit(messages)
+}
+
+// take empties the buffer. Must be called with the lock held.
+func (d *digest) take() []*dispatch.Message {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	messages := d.messages
+	d.messages = nil
+
+	return messages
+}
+
+func (d *digest) emit(messages []*dispatch.Message) {
+	if len(messages) == 0 {
+		return
+	}
+
+	summary := dispatch.NewGroupedSummaryMessage(fmt.Sprintf("Digest of %d messages", len(messages)), messages,
+		d.config.GroupBy)
+	d.send(summary.AnnotateContext(context.Background()), summary)
+}
@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	t.Run("sends digest after interval", func(t *testing.T) {
+		messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+			Name:   "digest",
+			Type:   "mock",
+			Digest: &dispatch.DigestConfig{Interval: dispatch.Duration(50 * time.Millisecond), GroupBy: "host"},
+		})
+
+		for _, host := range []string{"b", "a", "b"} {
+			err := messageService.QueueMessage(context.Background(),
+				dispatch.NewMessage("disk full", "message", map[string]string{"host": host}))
+			require.NoError(t, err)
+		}
+		assert.Empty(t, dispatcher.Messages())
+
+		assert.Eventually(t, func() bool { return len(dispatcher.Messages()) == 1 }, time.Second, time.Millisecond)
+		digest := dispatcher.Messages()[0]
+		assert.Equal(t, "Digest of 3 messages", digest.Title)
+		assert.Equal(t, "host=a (1)\n- disk full\n\nhost=b (2)\n- disk full\n- disk full\n", digest.Message)
+	})
+
+	t.Run("sends digest when full", func(t *testing.T) {
+		messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+			Name:   "digest",
+			Type:   "mock",
+			Digest: &dispatch.DigestConfig{Interval: dispatch.Duration(time.Hour), MaxSize: 2},
+		})
+
+		for range 3 {
+			err := messageService.QueueMessage(context.Background(), dispatch.NewMessage("title", "message", nil))
+			require.NoError(t, err)
+		}
+
+		require.Len(t, dispatcher.Messages(), 1)
+		assert.Equal(t, "Digest of 2 messages", dispatcher.Messages()[0].Title)
+
+		// the remainder is sent on shutdown
+		require.NoError(t, messageService.Shutdown(context.Background()))
+		require.Len(t, dispatcher.Messages(), 2)
+		assert.Equal(t, "Digest of 1 messages", dispatcher.Messages()[1].Title)
+	})
+
+	t.Run("requires interval", func(t *testing.T) {
+		messageService := service.NewMessageService(&MockRuleEngine{}, func(typeName string) (dispatch.Dispatcher, error) {
+			return &recordingDispatcher{}, nil
+		}, nil, nil)
+
+		err := messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{
+			Name:   "digest",
+			Type:   "mock",
+			Digest: &dispatch.DigestConfig{MaxSize: 2},
+		})
+		assert.ErrorIs(t, err, service.ErrDispatcherConfigInvalid)
+	})
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"dispatcherd/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrEscalationNotFound = errors.New("no pending escalation")
+
+// escalationKey identifies a pending escalation. A message matching several rules escalates through each of their
+// policies independently.
+type escalationKey struct {
+	messageID string
+	policy    string
+}
+
+type pendingEscalation struct {
+	escalation dispatch.Escalation
+	timer      *time.Timer
+}
+
+// escalator notifies the steps of an escalation policy one after another, until the message is acknowledged. Pending
+// escalations are persisted, so that they are resumed after a restart.
+type escalator struct {
+	logger *slog.Logger
+	repo   repository.EscalationRepository
+	notify func(ctx context.Context, message *dispatch.Message, dispatcherName string) error
+
+	mu       sync.Mutex
+	policies map[string]dispatch.EscalationPolicy
+	pending  map[escalationKey]*pendingEscalation
+	closed   bool
+}
+
+func newEscalator(repo repository.EscalationRepository,
+	notify func(ctx context.Context, message *dispatch.Message, dispatcherName string) error) *escalator {
+	return &escalator{
+		logger:   logging.GetLogger(logging.MessageProcessing),
+		repo:     repo,
+		notify:   notify,
+		policies: make(map[string]dispatch.EscalationPolicy),
+		pending:  make(map[escalationKey]*pendingEscalation),
+	}
+}
+
+func (e *escalator) setPolicy(policy dispatch.EscalationPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.policies[policy.Name] = policy
+}
+
+func (e *escalator) policy(name string) (dispatch.EscalationPolicy, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	policy, ok := e.policies[name]
+	return policy, ok
+}
+
+// start notifies the first step, and schedules the escalation to the next one.
+func (e *escalator) start(ctx context.Context, message *dispatch.Message, policy string,
+	steps []dispatch.EscalationStep) error {
+	if err := e.notify(ctx, message, steps[0].DispatcherName); err != nil {
+		return err
+	}
+
+	if len(steps) == 1 {
+		return nil
+	}
+
+	escalation := dispatch.Escalation{
+		Policy:  policy,
+		Message: message,
+		Steps:   steps,
+		Owner:   clientName(ctx),
+		NextAt:  time.Now().Add(steps[0].Timeout.Duration()),
+	}
+
+	e.mu.Lock()
+	e.schedule(ctx, escalation)
+	e.mu.Unlock()
+	e.logger.InfoContext(ctx, fmt.Sprintf("escalating with policy '%s' unless acknowledged", policy))
+
+	return nil
+}
+
+// schedule persists the escalation and starts the timer for its next step. Must be called with the lock held.
+func (e *escalator) schedule(ctx context.Context, escalation dispatch.Escalation) {
+	if e.closed {
+		return
+	}
+
+	key := escalationKey{messageID: escalation.Message.ID, policy: escalation.Policy}
+	if previous, ok := e.pending[key]; ok {
+		previous.timer.Stop()
+	}
+
+	e.save(ctx, escalation)
+	e.pending[key] = &pendingEscalation{
+		escalation: escalation,
+		timer: time.AfterFunc(time.Until(escalation.NextAt), func() {
+			e.escalate(key)
+		}),
+	}
+}
+
+// escalate notifies the next step of a pending escalation.
+func (e *escalator) escalate(key escalationKey) {
+	e.mu.Lock()
+	pending, ok := e.pending[key]
+	if !ok || e.closed {
+		// acknowledged in the meantime
+		e.mu.Unlock()
+		return
+	}
+
+	escalation := pending.escalation
+	escalation.Step++
+	step := escalation.Steps[escalation.Step]
+	ctx := escalation.Message.AnnotateContext(context.Background())
+
+	if escalation.Step+1 < len(escalation.Steps) {
+		escalation.NextAt = time.Now().Add(step.Timeout.Duration())
+		e.schedule(ctx, escalation)
+	} else {
+		delete(e.pending, key)
+		e.delete(ctx, key)
+	}
+	e.mu.Unlock()
+
+	e.logger.WarnContext(ctx, fmt.Sprintf("message not acknowledged, escalating to step %d of policy '%s'",
+		escalation.Step+1, escalation.Policy))
+	if err := e.notify(ctx, escalation.Message, step.DispatcherName); err != nil {
+		e.logger.ErrorContext(ctx, "failed to notify escalation step", logging.FieldError, err)
+	}
+}
+
+// acknowledge stops the pending escalations of a message, of all policies.
+func (e *escalator) acknowledge(ctx context.Context, messageID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var keys []escalationKey
+	for key, pending := range e.pending {
+		if key.messageID != messageID {
+			continue
+		}
+		if !isOwner(ctx, pending.escalation.Owner) {
+			return fmt.Errorf("escalation of message %s: %w", messageID, ErrOwnedByOtherClient)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return ErrEscalationNotFound
+	}
+
+	for _, key := range keys {
+		e.pending[key].timer.Stop()
+		delete(e.pending, key)
+		e.delete(ctx, key)
+	}
+	e.logger.InfoContext(ctx, fmt.Sprintf("message %s acknowledged, escalation stopped", messageID))
+
+	return nil
+}
+
+// restore resumes the persisted escalations. Steps which became due while the service was down are escalated
+// right away.
+func (e *escalator) restore(ctx context.Context) error {
+	if e.repo == nil {
+		return nil
+	}
+
+	escalations, err := e.repo.ListEscalations(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, escalation := range escalations {
+		e.schedule(escalation.Message.AnnotateContext(ctx), escalation)
+	}
+
+	return nil
+}
+
+// shutdown stops all timers. The persisted escalations are resumed on the next start.
+func (e *escalator) shutdown() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for _, pending := range e.pending {
+		pending.timer.Stop()
+	}
+}
+
+// save persists the escalation. Must be called with the lock held.
+func (e *escalator) save(ctx context.Context, escalation dispatch.Escalation) {
+	if e.repo == nil {
+		return
+	}
+
+	if err := e.repo.SaveEscalation(ctx, escalation); err != nil {
+		e.logger.ErrorContext(ctx, "failed to persist escalation", logging.FieldError, err)
+	}
+}
+
+// delete removes the persisted escalation. Must be called with the lock held.
+func (e *escalator) delete(ctx context.Context, key escalationKey) {
+	if e.repo == nil {
+		return
+	}
+
+	if err := e.repo.DeleteEscalation(ctx, key.messageID, key.policy); err != nil {
+		e.logger.ErrorContext(ctx, "failed to delete persisted escalation", logging.FieldError, err)
+	}
+}
@@ -0,0 +1,180 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/auth"
+	"dispatcherd/dispatch"
+	"dispatcherd/repository"
+	"dispatcherd/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEscalationService(t *testing.T, repo repository.EscalationRepository,
+	timeout time.Duration) (service.MessageService, map[string]*recordingDispatcher) {
+	t.Helper()
+
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{ID: "critical", EscalationPolicy: "on-call"}}, nil
+		},
+	}
+
+	// the dispatcher type doubles as name, to tell the dispatchers apart
+	dispatchers := map[string]*recordingDispatcher{}
+	messageService := service.NewMessageService(mre, func(typeName string) (dispatch.Dispatcher, error) {
+		dispatchers[typeName] = &recordingDispatcher{}
+		return dispatchers[typeName], nil
+	}, repo, nil)
+	for _, name := range []string{"primary", "secondary", "manager"} {
+		require.NoError(t, messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{Name: name, Type: name}))
+	}
+
+	require.NoError(t, messageService.LoadEscalationPolicy(dispatch.EscalationPolicy{
+		Name: "on-call",
+		Steps: []dispatch.EscalationStep{
+			{DispatcherName: "primary", Timeout: dispatch.Duration(timeout)},
+			{DispatcherName: "secondary", Timeout: dispatch.Duration(timeout)},
+			{DispatcherName: "manager"},
+		},
+	}))
+
+	return messageService, dispatchers
+}
+
+func TestEscalation(t *testing.T) {
+	t.Run("escalates until the last step", func(t *testing.T) {
+		messageService, dispatchers := setupEscalationService(t, nil, 20*time.Millisecond)
+
+		message := dispatch.NewMessage("database down", "message", nil)
+		require.NoError(t, messageService.QueueMessage(context.Background(), message))
+		assert.Len(t, dispatchers["primary"].Messages(), 1)
+		assert.Empty(t, dispatchers["secondary"].Messages())
+
+		assert.Eventually(t, func() bool { return len(dispatchers["manager"].Messages()) == 1 }, time.Second,
+			time.Millisecond)
+		assert.Len(t, dispatchers["secondary"].Messages(), 1)
+
+		// the escalation is over
+		assert.ErrorIs(t, messageService.AcknowledgeMessage(context.Background(), message.ID),
+			service.ErrEscalationNotFound)
+	})
+
+	t.Run("stops when acknowledged", func(t *testing.T) {
+		messageService, dispatchers := setupEscalationService(t, nil, 50*time.Millisecond)
+
+		message := dispatch.NewMessage("database down", "message", nil)
+		require.NoError(t, messageService.QueueMessage(context.Background(), message))
+		require.NoError(t, messageService.AcknowledgeMessage(context.Background(), message.ID))
+
+		time.Sleep(150 * time.Millisecond)
+		assert.Len(t, dispatchers["primary"].Messages(), 1)
+		assert.Empty(t, dispatchers["secondary"].Messages())
+		assert.Empty(t, dispatchers["manager"].Messages())
+	})
+
+	t.Run("only acknowledged by the sending client", func(t *testing.T) {
+		messageService, dispatchers := setupEscalationService(t, nil, 50*time.Millisecond)
+
+		teamA := auth.WithIdentity(context.Background(), &auth.Identity{Name: "team-a"})
+		teamB := auth.WithIdentity(context.Background(), &auth.Identity{Name: "team-b"})
+		message := dispatch.NewMessage("database down", "message", nil)
+		require.NoError(t, messageService.QueueMessage(teamA, message))
+
+		assert.ErrorIs(t, messageService.AcknowledgeMessage(teamB, message.ID), service.ErrOwnedByOtherClient)
+		require.NoError(t, messageService.AcknowledgeMessage(teamA, message.ID))
+
+		time.Sleep(150 * time.Millisecond)
+		assert.Empty(t, dispatchers["secondary"].Messages())
+	})
+
+	t.Run("escalates through several policies", func(t *testing.T) {
+		mre := &MockRuleEngine{
+			ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+				return []dispatch.Rule{
+					{ID: "critical", EscalationPolicy: "on-call"},
+					{ID: "database", EscalationPolicy: "database"},
+				}, nil
+			},
+		}
+
+		repo := repository.NewFilesystemEscalationRepository(t.TempDir())
+		dispatchers := map[string]*recordingDispatcher{}
+		messageService := service.NewMessageService(mre, func(typeName string) (dispatch.Dispatcher, error) {
+			dispatchers[typeName] = &recordingDispatcher{}
+			return dispatchers[typeName], nil
+		}, repo, nil)
+		for _, name := range []string{"primary", "secondary", "dba", "manager"} {
+			require.NoError(t, messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{Name: name, Type: name}))
+		}
+		timeout := dispatch.Duration(50 * time.Millisecond)
+		for _, policy := range []dispatch.EscalationPolicy{
+			{
+				Name:  "on-call",
+				Steps: []dispatch.EscalationStep{{DispatcherName: "primary", Timeout: timeout}, {DispatcherName: "secondary"}},
+			},
+			{
+				Name:  "database",
+				Steps: []dispatch.EscalationStep{{DispatcherName: "dba", Timeout: timeout}, {DispatcherName: "manager"}},
+			},
+		} {
+			require.NoError(t, messageService.LoadEscalationPolicy(policy))
+		}
+
+		message := dispatch.NewMessage("database down", "message", nil)
+		require.NoError(t, messageService.QueueMessage(context.Background(), message))
+		assert.Len(t, dispatchers["primary"].Messages(), 1)
+		assert.Len(t, dispatchers["dba"].Messages(), 1)
+
+		escalations, err := repo.ListEscalations(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, escalations, 2)
+
+		// acknowledging the message stops both escalations
+		require.NoError(t, messageService.AcknowledgeMessage(context.Background(), message.ID))
+		time.Sleep(150 * time.Millisecond)
+		assert.Empty(t, dispatchers["secondary"].Messages())
+		assert.Empty(t, dispatchers["manager"].Messages())
+
+		escalations, err = repo.ListEscalations(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, escalations)
+	})
+
+	t.Run("resumes after restart", func(t *testing.T) {
+		repo := repository.NewFilesystemEscalationRepository(t.TempDir())
+		messageService, _ := setupEscalationService(t, repo, 100*time.Millisecond)
+
+		message := dispatch.NewMessage("database down", "message", nil)
+		require.NoError(t, messageService.QueueMessage(context.Background(), message))
+		require.NoError(t, messageService.Shutdown(context.Background()))
+
+		restarted, dispatchers := setupEscalationService(t, repo, 100*time.Millisecond)
+		require.NoError(t, restarted.RestoreEscalations(context.Background()))
+
+		assert.Eventually(t, func() bool { return len(dispatchers["secondary"].Messages()) == 1 }, time.Second,
+			time.Millisecond)
+		assert.Empty(t, dispatchers["primary"].Messages())
+		assert.Equal(t, message.ID, dispatchers["secondary"].Messages()[0].ID)
+
+		require.NoError(t, restarted.AcknowledgeMessage(context.Background(), message.ID))
+		escalations, err := repo.ListEscalations(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, escalations)
+	})
+
+	t.Run("fails for unknown policy", func(t *testing.T) {
+		mre := &MockRuleEngine{
+			ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+				return []dispatch.Rule{{ID: "critical", EscalationPolicy: "unknown"}}, nil
+			},
+		}
+		messageService, _ := setupMessageService(t, mre, false)
+
+		err := messageService.QueueMessage(context.Background(), dispatch.NewMessage("title", "message", nil))
+		assert.ErrorIs(t, err, service.ErrEscalationPolicyNotFound)
+	})
+}
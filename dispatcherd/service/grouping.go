@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+type messageGroup struct {
+	key     string
+	members map[string]*dispatch.Message
+	order   []string
+	// whether members were added since the group was last sent
+	changed      bool
+	lastReceived time.Time
+	lastSent     time.Time
+	timer        *time.Timer
+}
+
+// grouper batches messages into groups identified by tag values, in the manner of the Alertmanager. The first message
+// of a group waits for siblings, afterwards new messages are sent once per group interval, and an unchanged group is
+// repeated after the repeat interval. A group expires once no message arrived for it for a whole interval.
+type grouper struct {
+	logger *slog.Logger
+	config *dispatch.GroupingConfig
+	send   func(ctx context.Context, message *dispatch.Message)
+
+	mu     sync.Mutex
+	groups map[string]*messageGroup
+}
+
+func newGrouper(config *dispatch.GroupingConfig, send func(ctx context.Context, message *dispatch.Message)) *grouper {
+	return &grouper{
+		logger: logging.GetLogger(logging.MessageProcessing),
+		config: config,
+		send:   send,
+		groups: make(map[string]*messageGroup),
+	}
+}
+
+func (g *grouper) add(ctx context.Context, message *dispatch.Message) {
+	key := g.groupKey(message)
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group, ok := g.groups[key]
+	if !ok {
+		group = &messageGroup{
+			key:     key,
+			members: make(map[string]*dispatch.Message),
+		}
+		group.timer = time.AfterFunc(g.config.GroupWait.Duration(), func() {
+			g.tick(group)
+		})
+		g.groups[key] = group
+		g.logger.DebugContext(ctx, fmt.Sprintf("created message group '%s'", key))
+	}
+
+	// the latest message replaces an earlier one with the same fingerprint
+	fingerprint := message.Fingerprint(nil)
+	if _, member := group.members[fingerprint]; !member {
+		group.order = append(group.order, fingerprint)
+		group.changed = true
+	}
+	group.members[fingerprint] = message
+	group.lastReceived = now
+}
+
+func (g *grouper) tick(group *messageGroup) {
+	now := time.Now()
+
+	g.mu.Lock()
+	if g.groups[group.key] != group {
+		// already flushed
+		g.mu.Unlock()
+		return
+	}
+
+	repeat := g.config.RepeatInterval.Duration()
+	repeatDue := repeat > 0 && now.Sub(group.lastSent) >= repeat && group.lastReceived.After(group.lastSent)
+
+	var summary *dispatch.Message
+	switch {
+	case group.changed || repeatDue:
+		summary = g.summarize(group)
+		group.changed = false
+		group.lastSent = now
+	case now.Sub(group.lastReceived) >= max(g.config.GroupInterval.Duration(), repeat):
+		delete(g.groups, group.key)
+		g.mu.Unlock()
+		g.logger.Debug(fmt.Sprintf("message group '%s' expired", group.key))
+		return
+	}
+
+	group.timer = time.AfterFunc(g.config.GroupInterval.Duration(), func() {
+		g.tick(group)
+	})
+	g.mu.Unlock()
+
+	if summary != nil {
+		g.send(summary.AnnotateContext(context.Background()), summary)
+	}
+}
+
+// flush sends the groups with pending changes and discards all groups.
+func (g *grouper) flush() {
+	g.mu.Lock()
+	groups := g.groups
+	g.groups = make(map[string]*messageGroup)
+
+	var summaries []*dispatch.Message
+	for group := range maps.Values(groups) {
+		group.timer.Stop()
+		if group.changed {
+			summaries = append(summaries, g.summarize(group))
+		}
+	}
+	g.mu.Unlock()
+
+	for _, summary := range summaries {
+		g.send(summary.AnnotateContext(context.Background()), summary)
+	}
+}
+
+// summarize combines the members of a group into one message. Must be called with the lock held.
+func (g *grouper) summarize(group *messageGroup) *dispatch.Message {
+	messages := make([]*dispatch.Message, 0, len(group.order))
+	for _, fingerprint := range group.order {
+		messages = append(messages, group.members[fingerprint])
+	}
+
+	title := fmt.Sprintf("%d messages", len(messages))
+	if group.key != "" {
+		title += " for " + group.key
+	}
+
+	return dispatch.NewSummaryMessage(title, messages)
+}
+
+func (g *grouper) groupKey(message *dispatch.Message) string {
+	labels := make([]string, 0, len(g.config.GroupBy))
+	for _, name := range g.config.GroupBy {
+		labels = append(labels, fmt.Sprintf("%s=%s", name, message.Tags[name]))
+	}
+
+	return strings.Join(labels, ", ")
+}
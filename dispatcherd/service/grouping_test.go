@@ -0,0 +1,118 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queueTagged(t *testing.T, messageService service.MessageService, title string, cluster string) {
+	t.Helper()
+	err := messageService.QueueMessage(context.Background(),
+		dispatch.NewMessage(title, "message", map[string]string{"cluster": cluster}))
+	require.NoError(t, err)
+}
+
+func TestGrouping(t *testing.T) {
+	t.Run("first message waits for siblings", func(t *testing.T) {
+		messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+			Name: "grouped",
+			Type: "mock",
+			Grouping: &dispatch.GroupingConfig{
+				GroupBy:       []string{"cluster"},
+				GroupWait:     dispatch.Duration(50 * time.Millisecond),
+				GroupInterval: dispatch.Duration(time.Hour),
+			},
+		})
+
+		queueTagged(t, messageService, "disk full", "a")
+		queueTagged(t, messageService, "high load", "a")
+		queueTagged(t, messageService, "disk full", "b")
+		assert.Empty(t, dispatcher.Messages())
+
+		assert.Eventually(t, func() bool { return len(dispatcher.Messages()) == 2 }, time.Second, time.Millisecond)
+		titles := []string{dispatcher.Messages()[0].Title, dispatcher.Messages()[1].Title}
+		assert.ElementsMatch(t, []string{"2 messages for cluster=a", "1 messages for cluster=b"}, titles)
+	})
+
+	t.Run("sends only changed groups", func(t *testing.T) {
+		messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+			Name: "grouped",
+			Type: "mock",
+			Grouping: &dispatch.GroupingConfig{
+				GroupBy:       []string{"cluster"},
+				GroupWait:     dispatch.Duration(10 * time.Millisecond),
+				GroupInterval: dispatch.Duration(50 * time.Millisecond),
+			},
+		})
+
+		queueTagged(t, messageService, "disk full", "a")
+		assert.Eventually(t, func() bool { return len(dispatcher.Messages()) == 1 }, time.Second, time.Millisecond)
+
+		// a repeated member does not change the group, a new one does
+		queueTagged(t, messageService, "disk full", "a")
+		queueTagged(t, messageService, "high load", "a")
+		assert.Eventually(t, func() bool { return len(dispatcher.Messages()) == 2 }, time.Second, time.Millisecond)
+		assert.Equal(t, "2 messages for cluster=a", dispatcher.Messages()[1].Title)
+		assert.Equal(t, "- disk full\n- high load\n", dispatcher.Messages()[1].Message)
+
+		// without repeat interval, unchanged groups are not sent again
+		time.Sleep(200 * time.Millisecond)
+		assert.Len(t, dispatcher.Messages(), 2)
+	})
+
+	t.Run("repeats unchanged group", func(t *testing.T) {
+		messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+			Name: "grouped",
+			Type: "mock",
+			Grouping: &dispatch.GroupingConfig{
+				GroupWait:      dispatch.Duration(10 * time.Millisecond),
+				GroupInterval:  dispatch.Duration(20 * time.Millisecond),
+				RepeatInterval: dispatch.Duration(60 * time.Millisecond),
+			},
+		})
+
+		assert.Eventually(t, func() bool {
+			queueTagged(t, messageService, "disk full", "a")
+			return len(dispatcher.Messages()) >= 2
+		}, 2*time.Second, 10*time.Millisecond)
+
+		for _, message := range dispatcher.Messages() {
+			assert.Equal(t, "1 messages", message.Title)
+		}
+	})
+
+	t.Run("sends pending groups on shutdown", func(t *testing.T) {
+		messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+			Name: "grouped",
+			Type: "mock",
+			Grouping: &dispatch.GroupingConfig{
+				GroupBy:       []string{"cluster"},
+				GroupWait:     dispatch.Duration(time.Hour),
+				GroupInterval: dispatch.Duration(time.Hour),
+			},
+		})
+
+		queueTagged(t, messageService, "disk full", "a")
+		require.NoError(t, messageService.Shutdown(context.Background()))
+		assert.Len(t, dispatcher.Messages(), 1)
+	})
+
+	t.Run("requires group interval", func(t *testing.T) {
+		messageService := service.NewMessageService(&MockRuleEngine{}, func(typeName string) (dispatch.Dispatcher, error) {
+			return &recordingDispatcher{}, nil
+		}, nil, nil)
+
+		err := messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{
+			Name:     "grouped",
+			Type:     "mock",
+			Grouping: &dispatch.GroupingConfig{GroupBy: []string{"cluster"}},
+		})
+		assert.ErrorIs(t, err, service.ErrDispatcherConfigInvalid)
+	})
+}
@@ -2,45 +2,93 @@ package service
 
 import (
 	"context"
+	"dispatcherd/auth"
 	"dispatcherd/dispatch"
 	"dispatcherd/logging"
+	"dispatcherd/repository"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
 var ErrDispatcherNotFound = errors.New("unknown dispatcher")
 var ErrDispatcherConfigInvalid = errors.New("invalid dispatcher config")
+var ErrEscalationPolicyNotFound = errors.New("unknown escalation policy")
+var ErrOwnedByOtherClient = errors.New("owned by another client")
+
+// how long the outlet of a replaced dispatcher config may take to deliver its queued messages
+const replacedOutletShutdownTimeout = 30 * time.Second
 
 type MessageService interface {
 	QueueMessage(ctx context.Context, message *dispatch.Message) error
+	EvaluateMessage(ctx context.Context, message *dispatch.Message) (dispatch.Evaluation, error)
 	LoadDispatcherConfig(config dispatch.DispatcherConfig) error
+	LoadEscalationPolicy(policy dispatch.EscalationPolicy) error
+	RestoreEscalations(ctx context.Context) error
+	RestoreSilences(ctx context.Context) error
+	AcknowledgeMessage(ctx context.Context, messageID string) error
+	ListAlerts(ctx context.Context) ([]dispatch.Alert, error)
+	CreateSilence(ctx context.Context, silence dispatch.Silence) (dispatch.Silence, error)
+	ListSilences(ctx context.Context) ([]dispatch.Silence, error)
+	Shutdown(ctx context.Context) error
 }
 
 type DispatcherFactoryFunc func(typeName string) (dispatch.Dispatcher, error)
 
+// route is a message as shaped by a matched rule, and the dispatcher it is delivered to.
+type route struct {
+	dispatcherName string
+	message        *dispatch.Message
+	transform      *dispatch.Transformation
+}
+
+// delivery is a dispatcher which received a message, and the transformation the message was shaped with.
+type delivery struct {
+	dispatcherName string
+	transform      *dispatch.Transformation
+}
+
 type messageService struct {
 	logger            *slog.Logger
 	ruleEngine        dispatch.RuleEngine
-	configs           map[string]dispatch.DispatcherConfig
 	validator         *validator.Validate
 	dispatcherFactory DispatcherFactoryFunc
+	alerts            *alertStore
+	silences          *silenceStore
+	escalations       *escalator
+
+	mu      sync.RWMutex
+	configs map[string]dispatch.DispatcherConfig
+	outlets map[string]*outlet
 }
 
-func NewMessageService(ruleEngine dispatch.RuleEngine, factoryFunc DispatcherFactoryFunc) MessageService {
-	return &messageService{
+// NewMessageService creates a message service. Pending escalations and silences are persisted to their repositories,
+// or only kept in memory if they are nil.
+func NewMessageService(ruleEngine dispatch.RuleEngine, factoryFunc DispatcherFactoryFunc,
+	escalationRepo repository.EscalationRepository, silenceRepo repository.SilenceRepository) MessageService {
+	s := &messageService{
 		logger:            logging.GetLogger(logging.MessageProcessing),
 		ruleEngine:        ruleEngine,
-		configs:           make(map[string]dispatch.DispatcherConfig),
 		validator:         validator.New(),
 		dispatcherFactory: factoryFunc,
+		alerts:            newAlertStore(),
+		silences:          newSilenceStore(silenceRepo),
+		configs:           make(map[string]dispatch.DispatcherConfig),
+		outlets:           make(map[string]*outlet),
 	}
+	s.escalations = newEscalator(escalationRepo, s.notifyDispatcher)
+
+	return s
 }
 
-func NewDefaultMessageService(ruleEngine dispatch.RuleEngine) MessageService {
-	return NewMessageService(ruleEngine, dispatch.DispatcherFactory)
+func NewDefaultMessageService(ruleEngine dispatch.RuleEngine, escalationRepo repository.EscalationRepository,
+	silenceRepo repository.SilenceRepository) MessageService {
+	return NewMessageService(ruleEngine, dispatch.DispatcherFactory, escalationRepo, silenceRepo)
 }
 
 func (s *messageService) QueueMessage(ctx context.Context, message *dispatch.Message) error {
@@ -48,47 +96,280 @@ func (s *messageService) QueueMessage(ctx context.Context, message *dispatch.Mes
 
 	s.logger.DebugContext(msgCtx, fmt.Sprintf("received message: %s", message.String()))
 
-	dispatcherNames, err := s.ruleEngine.ProcessMessage(msgCtx, message)
+	if message.Key != "" {
+		// only the client which fired an alert may update or resolve it
+		if owner, ok := s.alerts.owner(message.Key); ok && !isOwner(msgCtx, owner) {
+			s.logger.WarnContext(msgCtx, fmt.Sprintf("alert '%s' belongs to another client", message.Key))
+			return fmt.Errorf("alert '%s': %w", message.Key, ErrOwnedByOtherClient)
+		}
+	}
+
+	if silence, silenced := s.silences.match(message, clientName(msgCtx)); silenced {
+		s.logger.InfoContext(msgCtx, fmt.Sprintf("message silenced by silence %s", silence.ID))
+		s.recordSilenced(msgCtx, message)
+		return nil
+	}
+
+	if message.Key != "" && message.Status == dispatch.StatusResolved {
+		s.resolveAlert(msgCtx, message)
+		return nil
+	}
+
+	rules, err := s.ruleEngine.ProcessMessage(msgCtx, message)
 	if err != nil {
 		return fmt.Errorf("processing message: %w", err)
 	}
 
-	if len(dispatcherNames) == 0 {
-		// use default dispatcher
-		defaultDispatchers, err := s.getDefaultDispatchers()
+	var routes []route
+	var delivered []delivery
+	escalated := false
+	for _, rule := range rules {
+		// each rule gets its own copy of the message to transform
+		routed := rule.Transform.Apply(message)
+
+		if rule.EscalationPolicy == "" {
+			routes = append(routes, route{
+				dispatcherName: rule.DispatcherName,
+				message:        routed,
+				transform:      rule.Transform,
+			})
+			continue
+		}
+
+		notified, err := s.escalate(msgCtx, routed, rule.EscalationPolicy)
 		if err != nil {
-			s.logger.ErrorContext(msgCtx, "failed to get default dispatchers", logging.FieldError, err)
-			return fmt.Errorf("getting default dispatchers: %w", err)
+			return err
+		}
+		for _, name := range notified {
+			delivered = append(delivered, delivery{dispatcherName: name, transform: rule.Transform})
 		}
+		escalated = true
+	}
+
+	// enforce the dispatcher allow-list of the client
+	routes = slices.DeleteFunc(routes, func(r route) bool {
+		return !s.isDispatcherAllowed(msgCtx, r.dispatcherName)
+	})
 
-		if len(defaultDispatchers) > 0 {
-			s.invokeDispatchers(msgCtx, message, defaultDispatchers)
+	if len(routes) == 0 && escalated {
+		// escalations replace the default dispatchers
+		s.logger.InfoContext(msgCtx, "message dispatched")
+	} else if len(routes) == 0 && len(rules) > 0 {
+		// the default dispatchers must not be a way around the allow-list
+		s.logger.WarnContext(msgCtx, "message dropped, none of the matched dispatchers is allowed for the client")
+	} else if len(routes) == 0 {
+		// use default dispatcher
+		defaultOutlets := s.getDefaultOutlets(msgCtx)
+		if len(defaultOutlets) > 0 {
+			for _, name := range s.deliver(msgCtx, message, defaultOutlets) {
+				delivered = append(delivered, delivery{dispatcherName: name})
+			}
 			s.logger.InfoContext(msgCtx, "message dispatched using default dispatchers")
 		} else {
 			s.logger.WarnContext(msgCtx, "no dispatchers matched, and no default dispatcher is configured")
 		}
 	} else {
-		for _, dispatcherName := range dispatcherNames {
-			dispatcher, err := s.getDispatcherByName(dispatcherName)
+		for _, r := range routes {
+			o, err := s.getOutletByName(r.dispatcherName)
 			if err != nil {
-				s.logger.ErrorContext(msgCtx, "failed to get dispatcher "+dispatcherName, logging.FieldError, err)
-				return fmt.Errorf("getting dispatcher '%s': %w", dispatcherName, err)
+				s.logger.ErrorContext(msgCtx, "failed to get dispatcher "+r.dispatcherName, logging.FieldError, err)
+				return fmt.Errorf("getting dispatcher '%s': %w", r.dispatcherName, err)
+			}
+			for _, name := range s.deliver(msgCtx, r.message, []*outlet{o}) {
+				delivered = append(delivered, delivery{dispatcherName: name, transform: r.transform})
 			}
-			s.invokeDispatchers(msgCtx, message, []dispatch.Dispatcher{dispatcher})
 		}
 		s.logger.InfoContext(msgCtx, "message dispatched")
 	}
 
+	if message.Key != "" {
+		s.alerts.fire(message, clientName(msgCtx), delivered)
+	}
+
 	return nil
 }
 
-func (s *messageService) invokeDispatchers(ctx context.Context, message *dispatch.Message, dispatchers []dispatch.Dispatcher) {
-	for _, dispatcher := range dispatchers {
-		if err := dispatcher.Dispatch(ctx, message); err != nil {
+// EvaluateMessage explains how the message would be dispatched, without dispatching it.
+func (s *messageService) EvaluateMessage(ctx context.Context, message *dispatch.Message) (dispatch.Evaluation, error) {
+	evaluations, err := s.ruleEngine.EvaluateRules(ctx, message)
+	if err != nil {
+		return dispatch.Evaluation{}, fmt.Errorf("evaluating rules: %w", err)
+	}
+
+	evaluation := dispatch.Evaluation{
+		Rules:              evaluations,
+		Dispatchers:        []string{},
+		EscalationPolicies: []string{},
+	}
+
+	if silence, silenced := s.silences.peek(message, clientName(ctx)); silenced {
+		evaluation.SilencedBy = silence.ID
+	}
+
+	matched := false
+	for _, rule := range evaluations {
+		if !rule.Matched {
+			continue
+		}
+		matched = true
+
+		if rule.EscalationPolicy == "" {
+			if s.isDispatcherAllowed(ctx, rule.DispatcherName) {
+				evaluation.Dispatchers = append(evaluation.Dispatchers, rule.DispatcherName)
+			}
+			continue
+		}
+
+		evaluation.EscalationPolicies = append(evaluation.EscalationPolicies, rule.EscalationPolicy)
+		if policy, ok := s.escalations.policy(rule.EscalationPolicy); ok {
+			for _, step := range policy.Steps {
+				if s.isDispatcherAllowed(ctx, step.DispatcherName) {
+					evaluation.Dispatchers = append(evaluation.Dispatchers, step.DispatcherName)
+					break
+				}
+			}
+		}
+	}
+
+	if !matched {
+		evaluation.DefaultDispatchers = true
+		for _, o := range s.getDefaultOutlets(ctx) {
+			evaluation.Dispatchers = append(evaluation.Dispatchers, o.name)
+		}
+		slices.Sort(evaluation.Dispatchers)
+	}
+
+	return evaluation, nil
+}
+
+// escalate starts the escalation of a message, notifying the first step allowed for the client. It returns the
+// notified dispatchers.
+func (s *messageService) escalate(ctx context.Context, message *dispatch.Message, policyName string) ([]string, error) {
+	policy, ok := s.escalations.policy(policyName)
+	if !ok {
+		s.logger.ErrorContext(ctx, "failed to get escalation policy "+policyName)
+		return nil, fmt.Errorf("getting escalation policy '%s': %w", policyName, ErrEscalationPolicyNotFound)
+	}
+
+	steps := slices.DeleteFunc(slices.Clone(policy.Steps), func(step dispatch.EscalationStep) bool {
+		return !s.isDispatcherAllowed(ctx, step.DispatcherName)
+	})
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	if err := s.escalations.start(ctx, message, policy.Name, steps); err != nil {
+		s.logger.ErrorContext(ctx, "failed to dispatch message", logging.FieldError, err)
+		return nil, nil
+	}
+
+	return []string{steps[0].DispatcherName}, nil
+}
+
+func (s *messageService) notifyDispatcher(ctx context.Context, message *dispatch.Message, dispatcherName string) error {
+	o, err := s.getOutletByName(dispatcherName)
+	if err != nil {
+		return fmt.Errorf("getting dispatcher '%s': %w", dispatcherName, err)
+	}
+
+	return o.deliver(ctx, message)
+}
+
+func (s *messageService) LoadEscalationPolicy(policy dispatch.EscalationPolicy) error {
+	for _, step := range policy.Steps {
+		if _, err := s.getOutletByName(step.DispatcherName); err != nil {
+			return fmt.Errorf("getting dispatcher '%s': %w", step.DispatcherName, err)
+		}
+	}
+
+	s.escalations.setPolicy(policy)
+
+	return nil
+}
+
+func (s *messageService) RestoreEscalations(ctx context.Context) error {
+	return s.escalations.restore(ctx)
+}
+
+func (s *messageService) RestoreSilences(ctx context.Context) error {
+	return s.silences.restore(ctx)
+}
+
+func (s *messageService) AcknowledgeMessage(ctx context.Context, messageID string) error {
+	return s.escalations.acknowledge(ctx, messageID)
+}
+
+// recordSilenced keeps track of the alert state of a silenced message, without notifying anyone.
+func (s *messageService) recordSilenced(ctx context.Context, message *dispatch.Message) {
+	if message.Key == "" {
+		return
+	}
+
+	if message.Status == dispatch.StatusResolved {
+		s.alerts.resolve(message.Key)
+	} else {
+		s.alerts.fire(message, clientName(ctx), nil)
+	}
+}
+
+// resolveAlert closes the open alert of the message and notifies the dispatchers which received the firing message,
+// shaped by the same transformations.
+func (s *messageService) resolveAlert(ctx context.Context, message *dispatch.Message) {
+	alert, ok := s.alerts.resolve(message.Key)
+	if !ok {
+		s.logger.WarnContext(ctx, fmt.Sprintf("no open alert with key '%s' to resolve", message.Key))
+		return
+	}
+
+	for _, name := range alert.Dispatchers {
+		o, err := s.getOutletByName(name)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to get dispatcher "+name, logging.FieldError, err)
+			continue
+		}
+		if s.isDispatcherAllowed(ctx, name) {
+			s.deliver(ctx, alert.transforms[name].Apply(message), []*outlet{o})
+		}
+	}
+	s.logger.InfoContext(ctx, fmt.Sprintf("alert '%s' resolved", message.Key))
+}
+
+// deliver passes the message to the outlets, and returns the names of the dispatchers which accepted it.
+func (s *messageService) deliver(ctx context.Context, message *dispatch.Message, outlets []*outlet) []string {
+	var delivered []string
+	for _, o := range outlets {
+		if err := o.deliver(ctx, message); err != nil {
 			s.logger.ErrorContext(ctx, "failed to dispatch message", logging.FieldError, err)
 			break
 		}
+		delivered = append(delivered, o.name)
 	}
+
+	return delivered
+}
+
+func (s *messageService) ListAlerts(ctx context.Context) ([]dispatch.Alert, error) {
+	return s.alerts.list(), nil
+}
+
+func (s *messageService) CreateSilence(ctx context.Context, silence dispatch.Silence) (dispatch.Silence, error) {
+	// clients only mute their own messages, admins mute the messages of everyone
+	if identity, ok := auth.IdentityFromContext(ctx); ok && !identity.HasScope(auth.ScopeAdmin) {
+		silence.Owner = identity.Name
+	}
+
+	created, err := s.silences.add(ctx, silence)
+	if err != nil {
+		return dispatch.Silence{}, fmt.Errorf("persisting silence: %w", err)
+	}
+	s.logger.InfoContext(ctx, fmt.Sprintf("silence %s created by '%s' until %s", created.ID, created.CreatedBy,
+		created.EndsAt.Format(time.RFC3339)))
+
+	return created, nil
+}
+
+func (s *messageService) ListSilences(ctx context.Context) ([]dispatch.Silence, error) {
+	return s.silences.list(), nil
 }
 
 func (s *messageService) LoadDispatcherConfig(config dispatch.DispatcherConfig) error {
@@ -104,37 +385,109 @@ func (s *messageService) LoadDispatcherConfig(config dispatch.DispatcherConfig)
 		return ErrDispatcherConfigInvalid
 	}
 
+	if configValidator, ok := dispatcher.(dispatch.ConfigValidator); ok {
+		if err := configValidator.ValidateConfig(config.Config); err != nil {
+			return fmt.Errorf("%w: %w", ErrDispatcherConfigInvalid, err)
+		}
+	}
+
+	if config.Grouping != nil && (config.Grouping.GroupInterval <= 0 || config.Digest != nil) {
+		// groups need an interval to be sent, and are not combined with digests
+		return ErrDispatcherConfigInvalid
+	}
+
+	// a missing interval or window would silently turn off the digest or dedup
+	if (config.Digest != nil && config.Digest.Interval <= 0) || (config.Dedup != nil && config.Dedup.Window <= 0) {
+		return ErrDispatcherConfigInvalid
+	}
+
+	// load config into dispatcher
+	dispatcher.SetConfig(config.Config)
+
+	s.mu.Lock()
+	previous, replaced := s.outlets[config.Name]
 	s.configs[config.Name] = config
+	s.outlets[config.Name] = newOutlet(config, dispatcher)
+	s.mu.Unlock()
+
+	if replaced {
+		// deliver what is still queued for the previous config
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), replacedOutletShutdownTimeout)
+			defer cancel()
+			if err := previous.shutdown(ctx); err != nil {
+				s.logger.Error("failed to shutdown replaced dispatcher "+config.Name, logging.FieldError, err)
+			}
+		}()
+	}
+
 	return nil
 }
 
-func (s *messageService) getDispatcherByName(name string) (dispatch.Dispatcher, error) {
-	if config, ok := s.configs[name]; ok {
-		dispatcher, err := s.dispatcherFactory(config.Type)
-		if err != nil {
-			return nil, ErrDispatcherNotFound
+func (s *messageService) Shutdown(ctx context.Context) error {
+	// pending escalations are persisted and resumed on the next start
+	s.escalations.shutdown()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var errs []error
+	for name, o := range s.outlets {
+		if err := o.shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutting down dispatcher '%s': %w", name, err))
 		}
+	}
 
-		// load config into dispatcher
-		dispatcher.SetConfig(config.Config)
+	return errors.Join(errs...)
+}
 
-		return dispatcher, nil
-	} else {
-		return nil, ErrDispatcherNotFound
+func (s *messageService) getOutletByName(name string) (*outlet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if o, ok := s.outlets[name]; ok {
+		return o, nil
 	}
+
+	return nil, ErrDispatcherNotFound
 }
 
-func (s *messageService) getDefaultDispatchers() ([]dispatch.Dispatcher, error) {
-	defaultDispatchers := make([]dispatch.Dispatcher, 0)
+func (s *messageService) isDispatcherAllowed(ctx context.Context, name string) bool {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok || identity.Policy.AllowsDispatcher(name) {
+		return true
+	}
+
+	s.logger.WarnContext(ctx, fmt.Sprintf("dispatcher '%s' is not allowed for client '%s'", name, identity.Name))
+	return false
+}
+
+// clientName returns the name of the authenticated client, or an empty name without authentication.
+func clientName(ctx context.Context) string {
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		return identity.Name
+	}
+
+	return ""
+}
+
+// isOwner reports whether the client may change alerts and escalations of the given owner. Admins may change all of
+// them, and without authentication there is nobody to tell apart.
+func isOwner(ctx context.Context, owner string) bool {
+	identity, ok := auth.IdentityFromContext(ctx)
+	return !ok || identity.HasScope(auth.ScopeAdmin) || identity.Name == owner
+}
+
+func (s *messageService) getDefaultOutlets(ctx context.Context) []*outlet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	defaultOutlets := make([]*outlet, 0)
 	for _, config := range s.configs {
-		if config.IsDefault {
-			dispatcher, err := s.getDispatcherByName(config.Name)
-			if err != nil {
-				return nil, err
-			}
-			defaultDispatchers = append(defaultDispatchers, dispatcher)
+		if config.IsDefault && s.isDispatcherAllowed(ctx, config.Name) {
+			defaultOutlets = append(defaultOutlets, s.outlets[config.Name])
 		}
 	}
 
-	return defaultDispatchers, nil
+	return defaultOutlets
 }
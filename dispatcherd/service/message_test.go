@@ -2,8 +2,10 @@ package service_test
 
 import (
 	"context"
+	"dispatcherd/auth"
 	"dispatcherd/dispatch"
 	"dispatcherd/service"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,14 +25,14 @@ func setupMessageService(t *testing.T, re dispatch.RuleEngine, failFactory bool)
 		return dispatcher, nil
 	}
 
-	return service.NewMessageService(re, factory), dispatcher
+	return service.NewMessageService(re, factory, nil, nil), dispatcher
 }
 
 func TestCallDefaultDispatcher(t *testing.T) {
 	// mock rule engine to return no dispatchers
 	mre := &MockRuleEngine{
-		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]string, error) {
-			return []string{}, nil
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{}, nil
 		},
 	}
 
@@ -48,13 +50,13 @@ func TestCallDefaultDispatcher(t *testing.T) {
 	err = messageService.QueueMessage(context.Background(), &dispatch.Message{})
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, dispatcher.CallsCount)
+	assert.Equal(t, 1, dispatcher.CallsCount())
 }
 
 func TestCallNonDefaultDispatcher(t *testing.T) {
 	mre := &MockRuleEngine{
-		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]string, error) {
-			return []string{"non-default"}, nil
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{DispatcherName: "non-default"}}, nil
 		},
 	}
 
@@ -72,13 +74,35 @@ func TestCallNonDefaultDispatcher(t *testing.T) {
 	err = messageService.QueueMessage(context.Background(), &dispatch.Message{})
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, dispatcher.CallsCount)
+	assert.Equal(t, 1, dispatcher.CallsCount())
+}
+
+func TestQueueMessageConcurrently(t *testing.T) {
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{DispatcherName: "counter"}}, nil
+		},
+	}
+
+	messageService, dispatcher := setupMessageService(t, mre, false)
+	require.NoError(t, messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{Name: "counter", Type: "mock"}))
+
+	// the dispatcher is shared by all requests
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			assert.NoError(t, messageService.QueueMessage(context.Background(), dispatch.NewMessage("title", "", nil)))
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, dispatcher.CallsCount())
 }
 
 func TestNoDispatchersFound(t *testing.T) {
 	mre := &MockRuleEngine{
-		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]string, error) {
-			return []string{"test"}, nil
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{DispatcherName: "test"}}, nil
 		},
 	}
 
@@ -87,3 +111,144 @@ func TestNoDispatchersFound(t *testing.T) {
 	err := messageService.QueueMessage(context.Background(), &dispatch.Message{})
 	assert.Error(t, err)
 }
+
+func TestDispatcherNotAllowedForClient(t *testing.T) {
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{DispatcherName: "pager-team-b"}}, nil
+		},
+	}
+
+	dispatcherConfig := dispatch.DispatcherConfig{
+		Name:      "pager-team-b",
+		Type:      "mock",
+		IsDefault: false,
+		Config:    nil,
+	}
+
+	messageService, dispatcher := setupMessageService(t, mre, false)
+	err := messageService.LoadDispatcherConfig(dispatcherConfig)
+	require.NoError(t, err)
+
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{
+		Name:   "team-a",
+		Policy: &auth.Policy{AllowedDispatchers: []string{"mail-team-a"}},
+	})
+	err = messageService.QueueMessage(ctx, &dispatch.Message{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, dispatcher.CallsCount())
+}
+
+func TestNoDefaultDispatcherIfMatchedNotAllowed(t *testing.T) {
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{DispatcherName: "pager-team-b"}}, nil
+		},
+		EvaluateRulesFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.RuleEvaluation, error) {
+			return []dispatch.RuleEvaluation{{RuleID: "team-b", DispatcherName: "pager-team-b", Matched: true}}, nil
+		},
+	}
+
+	// the dispatcher type doubles as name, to tell the dispatchers apart
+	dispatchers := map[string]*recordingDispatcher{}
+	messageService := service.NewMessageService(mre, func(typeName string) (dispatch.Dispatcher, error) {
+		dispatchers[typeName] = &recordingDispatcher{}
+		return dispatchers[typeName], nil
+	}, nil, nil)
+	for _, config := range []dispatch.DispatcherConfig{
+		{Name: "pager-team-b", Type: "pager-team-b"},
+		{Name: "log", Type: "log", IsDefault: true},
+	} {
+		require.NoError(t, messageService.LoadDispatcherConfig(config))
+	}
+
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{
+		Name:   "team-a",
+		Policy: &auth.Policy{AllowedDispatchers: []string{"log"}},
+	})
+	require.NoError(t, messageService.QueueMessage(ctx, dispatch.NewMessage("title", "message", nil)))
+
+	assert.Empty(t, dispatchers["pager-team-b"].Messages())
+	assert.Empty(t, dispatchers["log"].Messages())
+
+	evaluation, err := messageService.EvaluateMessage(ctx, dispatch.NewMessage("title", "message", nil))
+	require.NoError(t, err)
+	assert.Empty(t, evaluation.Dispatchers)
+	assert.False(t, evaluation.DefaultDispatchers)
+}
+
+func TestRuleTransformations(t *testing.T) {
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{
+				{DispatcherName: "mail", Transform: &dispatch.Transformation{TitlePrefix: "[PROD] "}},
+				{DispatcherName: "log", Transform: &dispatch.Transformation{RemoveTags: []string{"host"}}},
+			}, nil
+		},
+	}
+
+	// the dispatcher type doubles as name, to tell the dispatchers apart
+	dispatchers := map[string]*recordingDispatcher{}
+	messageService := service.NewMessageService(mre, func(typeName string) (dispatch.Dispatcher, error) {
+		dispatchers[typeName] = &recordingDispatcher{}
+		return dispatchers[typeName], nil
+	}, nil, nil)
+	for _, name := range []string{"mail", "log"} {
+		require.NoError(t, messageService.LoadDispatcherConfig(dispatch.DispatcherConfig{Name: name, Type: name}))
+	}
+
+	message := dispatch.NewMessage("Disk full", "message", map[string]string{"host": "db-1"})
+	require.NoError(t, messageService.QueueMessage(context.Background(), message))
+
+	require.Len(t, dispatchers["mail"].Messages(), 1)
+	assert.Equal(t, "[PROD] Disk full", dispatchers["mail"].Messages()[0].Title)
+	assert.Equal(t, map[string]string{"host": "db-1"}, dispatchers["mail"].Messages()[0].Tags)
+
+	require.Len(t, dispatchers["log"].Messages(), 1)
+	assert.Equal(t, "Disk full", dispatchers["log"].Messages()[0].Title)
+	assert.Empty(t, dispatchers["log"].Messages()[0].Tags)
+}
+
+func TestEvaluateMessage(t *testing.T) {
+	engine := dispatch.NewRuleEngine()
+	engine.SetRules([]dispatch.Rule{
+		{
+			ID:             "critical",
+			DispatcherName: "pager",
+			Match:          []dispatch.RuleMatch{{TagName: "severity", Operator: dispatch.EQUALS, Value: "critical"}},
+		},
+	})
+
+	messageService, dispatcher := setupMessageService(t, engine, false)
+	for _, config := range []dispatch.DispatcherConfig{
+		{Name: "pager", Type: "mock"},
+		{Name: "log", Type: "mock", IsDefault: true},
+	} {
+		require.NoError(t, messageService.LoadDispatcherConfig(config))
+	}
+
+	t.Run("matching rule", func(t *testing.T) {
+		message := dispatch.NewMessage("title", "message", map[string]string{"severity": "critical"})
+		evaluation, err := messageService.EvaluateMessage(context.Background(), message)
+		require.NoError(t, err)
+
+		require.Len(t, evaluation.Rules, 1)
+		assert.True(t, evaluation.Rules[0].Matched)
+		assert.Equal(t, []string{"pager"}, evaluation.Dispatchers)
+		assert.False(t, evaluation.DefaultDispatchers)
+	})
+
+	t.Run("default dispatchers", func(t *testing.T) {
+		message := dispatch.NewMessage("title", "message", map[string]string{"severity": "info"})
+		evaluation, err := messageService.EvaluateMessage(context.Background(), message)
+		require.NoError(t, err)
+
+		assert.False(t, evaluation.Rules[0].Matched)
+		assert.Equal(t, []string{"log"}, evaluation.Dispatchers)
+		assert.True(t, evaluation.DefaultDispatchers)
+	})
+
+	// nothing is dispatched
+	assert.Equal(t, 0, dispatcher.CallsCount())
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"dispatcherd/ratelimit"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultMaxQueueLength bounds the queue of a throttled outlet without a configured maximum
+const defaultMaxQueueLength = 10000
+
+type queuedMessage struct {
+	ctx     context.Context
+	message *dispatch.Message
+}
+
+// outlet delivers messages to a single configured dispatcher. Outlets with an outbound rate limit queue the messages
+// and deliver them from a background worker at the configured rate.
+type outlet struct {
+	logger     *slog.Logger
+	name       string
+	dispatcher dispatch.Dispatcher
+	rateLimit  *dispatch.RateLimitConfig
+	limiter    *ratelimit.Limiter
+	dedup      *deduplicator
+	digest     *digest
+	grouper    *grouper
+
+	mu        sync.Mutex
+	pending   []queuedMessage
+	closed    bool
+	notify    chan struct{}
+	abort     chan struct{}
+	abortOnce sync.Once
+	done      chan struct{}
+}
+
+func newOutlet(config dispatch.DispatcherConfig, dispatcher dispatch.Dispatcher) *outlet {
+	o := &outlet{
+		logger:     logging.GetLogger(logging.MessageProcessing),
+		name:       config.Name,
+		dispatcher: dispatcher,
+		rateLimit:  config.RateLimit,
+		limiter:    ratelimit.NewLimiter(),
+		notify:     make(chan struct{}, 1),
+		abort:      make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if config.Digest != nil {
+		o.digest = newDigest(config.Digest, o.sendAndLog)
+	}
+
+	if config.Grouping != nil {
+		o.grouper = newGrouper(config.Grouping, o.sendAndLog)
+	}
+
+	if config.Dedup != nil {
+		o.dedup = newDeduplicator(config.Dedup, func(ctx context.Context, message *dispatch.Message) {
+			if err := o.collect(ctx, message); err != nil {
+				o.logger.ErrorContext(ctx, "failed to dispatch message", logging.FieldError, err)
+			}
+		})
+	}
+
+	if o.throttled() {
+		go o.run()
+	} else {
+		close(o.done)
+	}
+
+	return o
+}
+
+func (o *outlet) throttled() bool {
+	return o.rateLimit != nil && !o.rateLimit.Rate.IsZero()
+}
+
+// deliver passes the message on to the dispatcher, unless it is a suppressed repetition.
+func (o *outlet) deliver(ctx context.Context, message *dispatch.Message) error {
+	if o.dedup != nil && !o.dedup.admit(ctx, message) {
+		return nil
+	}
+
+	return o.collect(ctx, message)
+}
+
+// collect adds the message to its group or the digest, or sends it if the outlet has neither.
+func (o *outlet) collect(ctx context.Context, message *dispatch.Message) error {
+	if o.grouper != nil {
+		o.grouper.add(ctx, message)
+		return nil
+	}
+
+	if o.digest != nil {
+		o.digest.add(ctx, message)
+		return nil
+	}
+
+	return o.send(ctx, message)
+}
+
+func (o *outlet) sendAndLog(ctx context.Context, message *dispatch.Message) {
+	if err := o.send(ctx, message); err != nil {
+		o.logger.ErrorContext(ctx, "failed to dispatch message", logging.FieldError, err)
+	}
+}
+
+// send dispatches the message right away, or queues it if the outlet is throttled.
+func (o *outlet) send(ctx context.Context, message *dispatch.Message) error {
+	if !o.throttled() {
+		return o.dispatcher.Dispatch(ctx, message)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return fmt.Errorf("dispatcher '%s' is shutting down", o.name)
+	}
+
+	if len(o.pending) >= o.maxQueueLength() {
+		// a sustained burst over the rate would grow the queue without bound
+		dropped := o.pending[0]
+		o.pending = o.pending[1:]
+		o.logger.WarnContext(dropped.ctx, fmt.Sprintf("dropped oldest queued message, queue of dispatcher '%s' is full",
+			o.name))
+	}
+
+	// the request context is canceled once the response is sent, but its values are still useful for logging
+	o.pending = append(o.pending, queuedMessage{ctx: context.WithoutCancel(ctx), message: message})
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (o *outlet) maxQueueLength() int {
+	if o.rateLimit.MaxQueueLength > 0 {
+		return o.rateLimit.MaxQueueLength
+	}
+
+	return defaultMaxQueueLength
+}
+
+func (o *outlet) run() {
+	defer close(o.done)
+
+	for {
+		item, ok := o.next()
+		if !ok {
+			return
+		}
+
+		delay := o.limiter.Reserve(o.name, o.rateLimit.Limit)
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-o.abort:
+				timer.Stop()
+				o.drop(item)
+				return
+			}
+		}
+
+		if err := o.dispatcher.Dispatch(item.ctx, item.message); err != nil {
+			o.logger.ErrorContext(item.ctx, "failed to dispatch message", logging.FieldError, err)
+		}
+	}
+}
+
+// next waits for the next message to deliver. It returns false once the outlet is closed and the queue is drained.
+func (o *outlet) next() (queuedMessage, bool) {
+	for {
+		o.mu.Lock()
+		if len(o.pending) > 0 {
+			item := o.collapse()
+			o.mu.Unlock()
+			return item, true
+		}
+		closed := o.closed
+		o.mu.Unlock()
+
+		if closed {
+			return queuedMessage{}, false
+		}
+
+		select {
+		case <-o.notify:
+		case <-o.abort:
+			return queuedMessage{}, false
+		}
+	}
+}
+
+// collapse takes the next message from the queue. If the backlog exceeds the summary threshold, the whole backlog is
+// collapsed into a single summary message. Must be called with the lock held.
+func (o *outlet) collapse() queuedMessage {
+	threshold := o.rateLimit.SummaryThreshold
+	if threshold <= 0 || len(o.pending) <= threshold {
+		item := o.pending[0]
+		o.pending = o.pending[1:]
+		return item
+	}
+
+	messages := make([]*dispatch.Message, 0, len(o.pending))
+	for _, item := range o.pending {
+		messages = append(messages, item.message)
+	}
+	o.pending = nil
+
+	summary := dispatch.NewSummaryMessage(
+		fmt.Sprintf("%d messages collapsed by rate limit of '%s'", len(messages), o.name), messages)
+	ctx := summary.AnnotateContext(context.Background())
+	o.logger.WarnContext(ctx, fmt.Sprintf("collapsed %d queued messages into a summary", len(messages)))
+
+	return queuedMessage{ctx: ctx, message: summary}
+}
+
+func (o *outlet) drop(item queuedMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	dropped := len(o.pending) + 1
+	o.pending = nil
+	o.logger.ErrorContext(item.ctx, fmt.Sprintf("dropped %d queued messages of dispatcher '%s' on shutdown",
+		dropped, o.name))
+}
+
+// shutdown stops accepting messages and waits until the queue is drained. If the context expires first, the remaining
+// messages are dropped.
+func (o *outlet) shutdown(ctx context.Context) error {
+	// report pending repetitions, groups and digests while messages are still accepted
+	if o.dedup != nil {
+		o.dedup.flush()
+	}
+	if o.grouper != nil {
+		o.grouper.flush()
+	}
+	if o.digest != nil {
+		o.digest.flush()
+	}
+
+	// dispatchers keeping connections open release them once the queue is drained
+	if closer, ok := o.dispatcher.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				o.logger.Error("failed to close dispatcher "+o.name, logging.FieldError, err)
+			}
+		}()
+	}
+
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		select {
+		case o.notify <- struct{}{}:
+		default:
+		}
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		o.abortOnce.Do(func() { close(o.abort) })
+		<-o.done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/ratelimit"
+	"dispatcherd/service"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingDispatcher struct {
+	mu       sync.Mutex
+	messages []*dispatch.Message
+}
+
+func (d *recordingDispatcher) Dispatch(ctx context.Context, msg *dispatch.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages = append(d.messages, msg)
+	return nil
+}
+
+func (d *recordingDispatcher) ConfigSchema() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (d *recordingDispatcher) SetConfig(config map[string]interface{}) {}
+
+func (d *recordingDispatcher) Messages() []*dispatch.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*dispatch.Message{}, d.messages...)
+}
+
+func setupRecordingMessageService(t *testing.T, config dispatch.DispatcherConfig) (service.MessageService, *recordingDispatcher) {
+	t.Helper()
+
+	mre := &MockRuleEngine{
+		ProcessMessageFunc: func(ctx context.Context, msg *dispatch.Message) ([]dispatch.Rule, error) {
+			return []dispatch.Rule{{DispatcherName: config.Name}}, nil
+		},
+	}
+
+	dispatcher := &recordingDispatcher{}
+	messageService := service.NewMessageService(mre, func(typeName string) (dispatch.Dispatcher, error) {
+		return dispatcher, nil
+	}, nil, nil)
+	require.NoError(t, messageService.LoadDispatcherConfig(config))
+
+	return messageService, dispatcher
+}
+
+func TestOutboundRateLimit(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "throttled",
+		Type: "mock",
+		RateLimit: &dispatch.RateLimitConfig{
+			Limit: ratelimit.Limit{Rate: ratelimit.Rate{Events: 20, Per: time.Second}, Burst: 1},
+		},
+	})
+
+	start := time.Now()
+	for range 3 {
+		err := messageService.QueueMessage(context.Background(), dispatch.NewMessage("title", "message", nil))
+		require.NoError(t, err)
+	}
+
+	// messages beyond the rate are queued, not dropped
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, messageService.Shutdown(ctx))
+
+	assert.Len(t, dispatcher.Messages(), 3)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// no messages are accepted after shutdown
+	err := messageService.QueueMessage(context.Background(), dispatch.NewMessage("title", "message", nil))
+	assert.NoError(t, err)
+	assert.Len(t, dispatcher.Messages(), 3)
+}
+
+func TestOutboundRateLimitSummary(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "throttled",
+		Type: "mock",
+		RateLimit: &dispatch.RateLimitConfig{
+			Limit:            ratelimit.Limit{Rate: ratelimit.Rate{Events: 10, Per: time.Second}, Burst: 1},
+			SummaryThreshold: 2,
+		},
+	})
+
+	require.NoError(t, messageService.QueueMessage(context.Background(), dispatch.NewMessage("first", "message", nil)))
+	assert.Eventually(t, func() bool { return len(dispatcher.Messages()) == 1 }, time.Second, time.Millisecond)
+
+	for range 4 {
+		err := messageService.QueueMessage(context.Background(), dispatch.NewMessage("backlog", "message", nil))
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, messageService.Shutdown(ctx))
+
+	messages := dispatcher.Messages()
+	assert.Less(t, len(messages), 5)
+	summary := messages[len(messages)-1]
+	assert.Contains(t, summary.Title, "messages collapsed by rate limit of 'throttled'")
+	assert.Equal(t, 4, len(messages)-2+strings.Count(summary.Message, "- backlog"))
+}
+
+func TestOutboundRateLimitMaxQueueLength(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "throttled",
+		Type: "mock",
+		RateLimit: &dispatch.RateLimitConfig{
+			Limit:          ratelimit.Limit{Rate: ratelimit.Rate{Events: 20, Per: time.Second}, Burst: 1},
+			MaxQueueLength: 2,
+		},
+	})
+
+	for i := range 5 {
+		err := messageService.QueueMessage(context.Background(), dispatch.NewMessage(strconv.Itoa(i), "message", nil))
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, messageService.Shutdown(ctx))
+
+	// the oldest queued messages are dropped, the first ones may have been taken from the queue already
+	messages := dispatcher.Messages()
+	require.GreaterOrEqual(t, len(messages), 2)
+	assert.Less(t, len(messages), 5)
+	assert.Equal(t, "3", messages[len(messages)-2].Title)
+	assert.Equal(t, "4", messages[len(messages)-1].Title)
+}
+
+func TestOutboundRateLimitShutdownTimeout(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "throttled",
+		Type: "mock",
+		RateLimit: &dispatch.RateLimitConfig{
+			Limit: ratelimit.Limit{Rate: ratelimit.Rate{Events: 1, Per: time.Hour}, Burst: 1},
+		},
+	})
+
+	for range 3 {
+		err := messageService.QueueMessage(context.Background(), dispatch.NewMessage("title", "message", nil))
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, messageService.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Len(t, dispatcher.Messages(), 1)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"dispatcherd/dispatch"
+	"dispatcherd/logging"
+	"dispatcherd/repository"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// silenceStore keeps the active and upcoming silences. Expired silences are removed once they end. Silences are
+// persisted, so that they survive a restart.
+type silenceStore struct {
+	logger *slog.Logger
+	repo   repository.SilenceRepository
+
+	mu       sync.Mutex
+	silences map[string]*dispatch.Silence
+}
+
+func newSilenceStore(repo repository.SilenceRepository) *silenceStore {
+	return &silenceStore{
+		logger:   logging.GetLogger(logging.MessageProcessing),
+		repo:     repo,
+		silences: make(map[string]*dispatch.Silence),
+	}
+}
+
+func (s *silenceStore) add(ctx context.Context, silence dispatch.Silence) (dispatch.Silence, error) {
+	now := time.Now()
+	silence.ID = uuid.New().String()
+	silence.CreatedAt = now
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repo != nil {
+		if err := s.repo.SaveSilence(ctx, silence); err != nil {
+			return dispatch.Silence{}, err
+		}
+	}
+	s.schedule(silence)
+
+	return s.withStatus(silence, now), nil
+}
+
+// schedule keeps the silence until it ends. Must be called with the lock held.
+func (s *silenceStore) schedule(silence dispatch.Silence) {
+	s.silences[silence.ID] = &silence
+	time.AfterFunc(time.Until(silence.EndsAt), func() {
+		s.expire(silence.ID)
+	})
+}
+
+func (s *silenceStore) expire(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.silences, id)
+	s.delete(context.Background(), id)
+}
+
+// restore loads the persisted silences, dropping the ones which ended while the service was down.
+func (s *silenceStore) restore(ctx context.Context) error {
+	if s.repo == nil {
+		return nil
+	}
+
+	silences, err := s.repo.ListSilences(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, silence := range silences {
+		if !now.Before(silence.EndsAt) {
+			s.delete(ctx, silence.ID)
+			continue
+		}
+		s.schedule(silence)
+	}
+
+	return nil
+}
+
+// delete removes the persisted silence. Must be called with the lock held.
+func (s *silenceStore) delete(ctx context.Context, id string) {
+	if s.repo == nil {
+		return
+	}
+
+	if err := s.repo.DeleteSilence(ctx, id); err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete persisted silence", logging.FieldError, err)
+	}
+}
+
+// match returns the first silence muting the message of the client, and counts the message as silenced.
+func (s *silenceStore) match(message *dispatch.Message, client string) (dispatch.Silence, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence := s.find(message, client)
+	if silence == nil {
+		return dispatch.Silence{}, false
+	}
+	silence.Silenced++
+
+	return *silence, true
+}
+
+// peek returns the first silence muting the message of the client, without counting the message.
+func (s *silenceStore) peek(message *dispatch.Message, client string) (dispatch.Silence, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence := s.find(message, client)
+	if silence == nil {
+		return dispatch.Silence{}, false
+	}
+
+	return *silence, true
+}
+
+// find returns the first silence muting the message of the client. Must be called with the lock held.
+func (s *silenceStore) find(message *dispatch.Message, client string) *dispatch.Silence {
+	now := time.Now()
+	for _, silence := range s.silences {
+		if (silence.Owner == "" || silence.Owner == client) && silence.Matches(message, now) {
+			return silence
+		}
+	}
+
+	return nil
+}
+
+// list returns the active and upcoming silences, ordered by their start.
+func (s *silenceStore) list() []dispatch.Silence {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silences := make([]dispatch.Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		if now.Before(silence.EndsAt) {
+			silences = append(silences, s.withStatus(*silence, now))
+		}
+	}
+
+	slices.SortFunc(silences, func(a, b dispatch.Silence) int {
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+
+	return silences
+}
+
+func (s *silenceStore) withStatus(silence dispatch.Silence, now time.Time) dispatch.Silence {
+	silence.Matchers = slices.Clone(silence.Matchers)
+	silence.Status = dispatch.SilencePending
+	if silence.IsActive(now) {
+		silence.Status = dispatch.SilenceActive
+	}
+
+	return silence
+}
@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"context"
+	"dispatcherd/auth"
+	"dispatcherd/dispatch"
+	"dispatcherd/repository"
+	"dispatcherd/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSilences(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "ops",
+		Type: "mock",
+	})
+	ctx := context.Background()
+
+	active, err := messageService.CreateSilence(ctx, dispatch.Silence{
+		Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-1"}},
+		EndsAt:    time.Now().Add(100 * time.Millisecond),
+		CreatedBy: "operator",
+		Comment:   "maintenance",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, active.ID)
+	assert.Equal(t, dispatch.SilenceActive, active.Status)
+
+	upcoming, err := messageService.CreateSilence(ctx, dispatch.Silence{
+		Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-2"}},
+		StartsAt:  time.Now().Add(time.Hour),
+		EndsAt:    time.Now().Add(2 * time.Hour),
+		CreatedBy: "operator",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dispatch.SilencePending, upcoming.Status)
+
+	silenced := dispatch.NewMessage("disk full", "message", map[string]string{"host": "db-1"})
+	silenced.Key = "disk-db-1"
+	silenced.Status = dispatch.StatusFiring
+	require.NoError(t, messageService.QueueMessage(ctx, silenced))
+	require.NoError(t, messageService.QueueMessage(ctx,
+		dispatch.NewMessage("disk full", "message", map[string]string{"host": "db-2"})))
+
+	// only the message of the active silence is muted, but its alert is still recorded
+	require.Len(t, dispatcher.Messages(), 1)
+	assert.Equal(t, "db-2", dispatcher.Messages()[0].Tags["host"])
+	alerts, err := messageService.ListAlerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Empty(t, alerts[0].Dispatchers)
+
+	silences, err := messageService.ListSilences(ctx)
+	require.NoError(t, err)
+	require.Len(t, silences, 2)
+	assert.Equal(t, active.ID, silences[0].ID)
+	assert.Equal(t, 1, silences[0].Silenced)
+	assert.Equal(t, upcoming.ID, silences[1].ID)
+
+	// expired silences are removed
+	assert.Eventually(t, func() bool {
+		silences, err := messageService.ListSilences(ctx)
+		return err == nil && len(silences) == 1
+	}, time.Second, time.Millisecond)
+	require.NoError(t, messageService.QueueMessage(ctx,
+		dispatch.NewMessage("disk full", "message", map[string]string{"host": "db-1"})))
+	assert.Len(t, dispatcher.Messages(), 2)
+}
+
+func TestSilencesOfClient(t *testing.T) {
+	messageService, dispatcher := setupRecordingMessageService(t, dispatch.DispatcherConfig{
+		Name: "ops",
+		Type: "mock",
+	})
+
+	teamA := auth.WithIdentity(context.Background(), &auth.Identity{Name: "team-a"})
+	teamB := auth.WithIdentity(context.Background(), &auth.Identity{Name: "team-b"})
+
+	silence, err := messageService.CreateSilence(teamA, dispatch.Silence{
+		Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-1"}},
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "operator",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", silence.Owner)
+
+	// the silence of team-a does not mute the messages of team-b
+	message := dispatch.NewMessage("disk full", "message", map[string]string{"host": "db-1"})
+	require.NoError(t, messageService.QueueMessage(teamA, message))
+	require.NoError(t, messageService.QueueMessage(teamB, message))
+	require.Len(t, dispatcher.Messages(), 1)
+}
+
+func TestSilencesRestore(t *testing.T) {
+	repo := repository.NewFilesystemSilenceRepository(t.TempDir())
+	newService := func() service.MessageService {
+		return service.NewMessageService(&MockRuleEngine{}, func(typeName string) (dispatch.Dispatcher, error) {
+			return &recordingDispatcher{}, nil
+		}, nil, repo)
+	}
+
+	messageService := newService()
+	created, err := messageService.CreateSilence(context.Background(), dispatch.Silence{
+		Matchers:  []dispatch.RuleMatch{{TagName: "host", Operator: dispatch.EQUALS, Value: "db-1"}},
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "operator",
+	})
+	require.NoError(t, err)
+
+	restarted := newService()
+	require.NoError(t, restarted.RestoreSilences(context.Background()))
+	silences, err := restarted.ListSilences(context.Background())
+	require.NoError(t, err)
+	require.Len(t, silences, 1)
+	assert.Equal(t, created.ID, silences[0].ID)
+}
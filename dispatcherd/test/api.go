@@ -2,6 +2,7 @@ package test
 
 import (
 	"bytes"
+	"dispatcherd/auth"
 	"dispatcherd/handler"
 	"encoding/json"
 	"io"
@@ -80,6 +81,11 @@ func (r *APIRunner) WithPath(key, value string) *APIRunner {
 	return r
 }
 
+func (r *APIRunner) WithIdentity(identity *auth.Identity) *APIRunner {
+	r.req = r.req.WithContext(auth.WithIdentity(r.req.Context(), identity))
+	return r
+}
+
 func (r *APIRunner) Run(t *testing.T) *Result {
 	err := r.handlerFunc(r.rr, r.req)
 	return &Result{
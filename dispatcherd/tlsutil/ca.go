@@ -0,0 +1,24 @@
+package tlsutil
+
+import (
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+var ErrNoCertificatesInCAFile = errors.New("no certificates found in CA file")
+
+// LoadCertPool reads a CA bundle in PEM format.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, ErrNoCertificatesInCAFile
+	}
+
+	return pool, nil
+}